@@ -2,17 +2,56 @@ package main
 
 import (
 	"context"
-	"fmt"
+	"encoding/json"
+	"log/slog"
+	"sync"
+
+	"tetris-desktop/backend/server"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// stateEvent carries game states to the frontend, aiStateEvent those of
+// the game the AI plays
+const (
+	stateEvent   = "game:state"
+	aiStateEvent = "ai:state"
 )
 
-// App struct
+// App is bound to the frontend, which calls its exported methods instead of
+// the HTTP API so the desktop app works without a TCP port
 type App struct {
-	ctx context.Context
+	ctx    context.Context
+	srv    *server.Server
+	apiErr error                        // why the HTTP API asked for with --api is not served
+	emit   func(event string, data any) // sends an event to the frontend
+
+	gameMu sync.Mutex
+	game   *server.ChanConn
+	ai     *server.ChanConn
+}
+
+// GameOptions choose the game StartGame begins
+type GameOptions struct {
+	Mode    string `json:"mode"`
+	Lines   int    `json:"lines"`
+	Minutes int    `json:"minutes"`
+	Save    string `json:"save"` // id of a saved game to resume
 }
 
-// NewApp creates a new App application struct
-func NewApp() *App {
-	return &App{}
+// AIOptions choose the game StartAI has the AI play
+type AIOptions struct {
+	Mode    string `json:"mode"`
+	Lines   int    `json:"lines"`
+	Minutes int    `json:"minutes"`
+	Level   string `json:"level"` // difficulty, the default bot when empty
+}
+
+// NewApp creates a new App application struct around the backend server
+func NewApp(srv *server.Server) *App {
+	a := &App{srv: srv}
+	a.emit = func(event string, data any) { runtime.EventsEmit(a.ctx, event, data) }
+	return a
 }
 
 // startup is called when the app starts. The context is saved
@@ -21,7 +60,198 @@ func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
 }
 
-// Greet returns a greeting for the given name
-func (a *App) Greet(name string) string {
-	return fmt.Sprintf("Hello %s, It's show time!", name)
+// domReady is called once the first page has loaded; it reports an HTTP
+// API that could not be started, as the window can show dialogs by now
+func (a *App) domReady(ctx context.Context) {
+	if a.apiErr == nil {
+		return
+	}
+	slog.Error("HTTP API not started", "err", a.apiErr)
+	_, err := runtime.MessageDialog(ctx, runtime.MessageDialogOptions{
+		Type:  runtime.ErrorDialog,
+		Title: "Backend server not started",
+		Message: "The game works as usual, but bots and other clients cannot connect to it.\n\n" +
+			a.apiErr.Error(),
+	})
+	if err != nil {
+		slog.Warn("Error dialog not shown", "err", err)
+	}
+	a.apiErr = nil
+}
+
+// quit asks Wails to close the window, which ends in shutdown
+func (a *App) quit() {
+	if a.ctx == nil {
+		return
+	}
+	runtime.Quit(a.ctx)
+}
+
+// shutdown is called when the app is closing; it stops the backend server
+// and saves its data before the process exits. The server closes the
+// running game itself, once it is stopping, so the game is saved.
+func (a *App) shutdown(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, server.ShutdownTimeout)
+	defer cancel()
+	if err := a.srv.Shutdown(ctx); err != nil {
+		slog.Error("Shutdown incomplete", "err", err)
+	}
+	a.StopGame()
+	a.StopAI()
+}
+
+// StartGame begins a game, ending any running one. Its states arrive as
+// "game:state" events and inputs go through GameInput.
+func (a *App) StartGame(opts GameOptions) {
+	a.gameMu.Lock()
+	defer a.gameMu.Unlock()
+	if a.game != nil {
+		a.game.Close()
+	}
+	conn := server.NewChanConn(func(v any) error {
+		a.emit(stateEvent, v)
+		return nil
+	})
+	a.game = conn
+	go a.srv.RunSession(conn, server.SessionOptions{
+		Mode: a.srv.Mode(opts.Mode, opts.Lines, opts.Minutes),
+		Save: opts.Save,
+	})
+}
+
+// GameInput passes a message to the running game, in the same form as the
+// websocket messages: {"type":"move","dir":"left"}, {"type":"restart"}, ...
+func (a *App) GameInput(msg map[string]any) error {
+	a.gameMu.Lock()
+	game := a.game
+	a.gameMu.Unlock()
+	if game == nil {
+		return nil
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return game.Input(data)
+}
+
+// StopGame ends the running game, if any
+func (a *App) StopGame() {
+	a.gameMu.Lock()
+	defer a.gameMu.Unlock()
+	if a.game != nil {
+		a.game.Close()
+		a.game = nil
+	}
+}
+
+// StartAI begins a game played by the AI, as a versus opponent or a demo,
+// ending any running one. Its states arrive as "ai:state" events and
+// AIInput passes "pause/resume" and "restart" messages.
+func (a *App) StartAI(opts AIOptions) {
+	a.gameMu.Lock()
+	defer a.gameMu.Unlock()
+	if a.ai != nil {
+		a.ai.Close()
+	}
+	conn := server.NewChanConn(func(v any) error {
+		a.emit(aiStateEvent, v)
+		return nil
+	})
+	a.ai = conn
+	go a.srv.RunAI(conn, server.AIBot(opts.Level), server.SessionOptions{
+		Mode: a.srv.Mode(opts.Mode, opts.Lines, opts.Minutes),
+	})
+}
+
+// AIInput passes a message to the AI's game, like GameInput
+func (a *App) AIInput(msg map[string]any) error {
+	a.gameMu.Lock()
+	conn := a.ai
+	a.gameMu.Unlock()
+	if conn == nil {
+		return nil
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return conn.Input(data)
+}
+
+// StopAI ends the AI's game, if any
+func (a *App) StopAI() {
+	a.gameMu.Lock()
+	defer a.gameMu.Unlock()
+	if a.ai != nil {
+		a.ai.Close()
+		a.ai = nil
+	}
+}
+
+// BackendURL returns where the HTTP API is served, for connecting other
+// clients to this game, or "" when it is not
+func (a *App) BackendURL() string {
+	return a.srv.URL()
+}
+
+// BackendToken returns the access token other clients must send to the
+// HTTP API, or "" when it is not required
+func (a *App) BackendToken() string {
+	return a.srv.Token()
+}
+
+// Highscores returns one leaderboard in ranking order
+func (a *App) Highscores(mode string, lines, minutes int) []server.Highscore {
+	return a.srv.Highscores(mode, lines, minutes)
+}
+
+// SubmitHighscore ranks a finished game on its leaderboard
+func (a *App) SubmitHighscore(sub server.ScoreSubmission) error {
+	return a.srv.AddHighscore(sub)
+}
+
+// Modes returns the modes on offer
+func (a *App) Modes() *server.ModeSet {
+	return a.srv.Modes()
+}
+
+// Settings returns the stored preferences
+func (a *App) Settings() server.Settings {
+	return a.srv.Settings()
+}
+
+// SaveSettings replaces the stored preferences
+func (a *App) SaveSettings(set server.Settings) error {
+	return a.srv.SaveSettings(set)
+}
+
+// Stats returns the lifetime statistics of finished games
+func (a *App) Stats() server.LifetimeStats {
+	return a.srv.Stats()
+}
+
+// Saves lists the saved games, newest first
+func (a *App) Saves() ([]server.Replay, error) {
+	return a.srv.Saves()
+}
+
+// DeleteSave removes a saved game
+func (a *App) DeleteSave(id string) error {
+	return a.srv.DeleteSave(id)
+}
+
+// Replays lists the recorded finished games, newest first
+func (a *App) Replays() ([]server.Replay, error) {
+	return a.srv.Replays()
+}
+
+// Replay returns one recorded game with its events
+func (a *App) Replay(id string) (*server.Replay, error) {
+	return a.srv.Replay(id)
+}
+
+// DeleteReplay removes a recorded game
+func (a *App) DeleteReplay(id string) error {
+	return a.srv.DeleteReplay(id)
 }
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"tetris-desktop/backend/model"
+	"tetris-desktop/backend/server"
+)
+
+func TestShutdownSavesRunningGame(t *testing.T) {
+	dir := t.TempDir()
+	srv := server.New()
+	if err := srv.Setup(server.Config{DataDir: dir}); err != nil {
+		t.Fatal(err)
+	}
+	app := NewApp(srv)
+	states := make(chan any, 16)
+	app.emit = func(event string, data any) {
+		select {
+		case states <- data:
+		default:
+		}
+	}
+	next := func() {
+		t.Helper()
+		select {
+		case <-states:
+		case <-time.After(5 * time.Second):
+			t.Fatal("no state sent")
+		}
+	}
+
+	app.StartGame(GameOptions{Mode: "classic"})
+	next()
+	if err := app.GameInput(map[string]any{"type": "move", "dir": "left"}); err != nil {
+		t.Fatal(err)
+	}
+	next()
+
+	app.shutdown(context.Background())
+	saves, err := filepath.Glob(filepath.Join(dir, "saves", "*.json"))
+	if err != nil || len(saves) != 1 {
+		entries, _ := os.ReadDir(dir)
+		t.Fatalf("got saves %v (%v), want one; data dir holds %v", saves, err, entries)
+	}
+}
+
+func TestAIPlaysBesideTheGame(t *testing.T) {
+	srv := server.New()
+	if err := srv.Setup(server.Config{DataDir: t.TempDir()}); err != nil {
+		t.Fatal(err)
+	}
+	app := NewApp(srv)
+	events := make(chan string, 64)
+	pieces := make(chan int, 64)
+	app.emit = func(event string, data any) {
+		select {
+		case events <- event:
+		default:
+		}
+		if s, ok := data.(*model.GameState); ok && event == aiStateEvent {
+			select {
+			case pieces <- s.Pieces:
+			default:
+			}
+		}
+	}
+
+	app.StartGame(GameOptions{Mode: "classic"})
+	app.StartAI(AIOptions{Mode: "classic", Level: "hard"})
+	defer app.shutdown(context.Background())
+	seen := map[string]bool{}
+	timeout := time.After(5 * time.Second)
+	for !seen[stateEvent] || !seen[aiStateEvent] {
+		select {
+		case ev := <-events:
+			seen[ev] = true
+		case <-timeout:
+			t.Fatalf("got events %v, want the game's and the AI's", seen)
+		}
+	}
+	for {
+		select {
+		case n := <-pieces:
+			if n > 0 {
+				return
+			}
+		case <-timeout:
+			t.Fatal("AI placed no piece")
+		}
+	}
+}
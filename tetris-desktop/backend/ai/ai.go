@@ -0,0 +1,229 @@
+// Package ai is a computer player. It scores every placement of the falling
+// piece by the shape of the stack it leaves behind, optionally looking ahead
+// through the preview, and plays the best one at a set pace.
+package ai
+
+import (
+	"math"
+	"time"
+
+	"tetris-desktop/backend/model"
+)
+
+// Weights scale each feature of the stack left after a placement. Features
+// that make the stack worse should get negative weights.
+type Weights struct {
+	Height    float64 `json:"height"`    // sum of column heights
+	Lines     float64 `json:"lines"`     // lines cleared by the placement
+	Holes     float64 `json:"holes"`     // empty cells with a filled cell above
+	Bumpiness float64 `json:"bumpiness"` // sum of height steps between columns
+	Wells     float64 `json:"wells"`     // sum of depths of one-wide wells
+}
+
+// DefaultWeights play a steady, safe game
+var DefaultWeights = Weights{
+	Height:    -0.51,
+	Lines:     0.76,
+	Holes:     -0.36,
+	Bumpiness: -0.18,
+	Wells:     -0.05,
+}
+
+const (
+	defaultPPS = 2
+	maxPPS     = 30
+	// maxLookAhead caps how many preview pieces the bot searches through
+	maxLookAhead = 2
+)
+
+// Bot chooses and plays placements
+type Bot struct {
+	Weights   Weights
+	LookAhead int     // preview pieces to search through after the falling one
+	PPS       float64 // pieces placed per second by Play
+	UseHold   bool    // also consider swapping with the hold slot
+}
+
+// New returns a bot with the default weights, one piece of look-ahead and a
+// relaxed pace
+func New() *Bot {
+	return &Bot{Weights: DefaultWeights, LookAhead: 1, PPS: defaultPPS, UseHold: true}
+}
+
+// Levels are the difficulty presets, easiest first. Easier bots care less
+// about holes and a flat stack, search fewer pieces and play slower; the
+// hardest also saves up its line clears to score more for them.
+var Levels = []string{"easy", "normal", "hard"}
+
+var levels = map[string]Bot{
+	"easy": {
+		Weights: Weights{Height: -0.3, Lines: 0.2, Holes: -0.08, Bumpiness: -0.02},
+		PPS:     1,
+	},
+	"normal": *New(),
+	"hard": {
+		Weights:   Weights{Height: -0.51, Lines: 0.2, Holes: -0.5, Bumpiness: -0.18},
+		LookAhead: 1,
+		PPS:       3,
+		UseHold:   true,
+	},
+}
+
+// Level returns a bot playing at the named difficulty, or false when there
+// is no such level
+func Level(name string) (*Bot, bool) {
+	b, ok := levels[name]
+	return &b, ok
+}
+
+// Move is the bot's choice: optionally hold first, then place
+type Move struct {
+	Hold      bool            `json:"hold"`
+	Placement model.Placement `json:"placement"`
+}
+
+// Best returns the best move for the falling piece of g, or false when the
+// game has nothing to place. g itself is left untouched. Like a player, the
+// bot only knows the pieces the mode shows: it searches no further than the
+// preview, and without one it does not hold into an empty slot, which would
+// bring in the hidden next piece.
+func (b *Bot) Best(g *model.Game) (Move, bool) {
+	c := g.Clone()
+	shown := c.Mode.ShownPreview()
+	depth := min(max(b.LookAhead, 0), maxLookAhead, shown)
+	best, found := Move{}, false
+	bestScore := math.Inf(-1)
+
+	try := func(c *model.Game, hold bool, depth int) {
+		for _, p := range c.Placements() {
+			if score := b.score(c, p, depth); !found || score > bestScore {
+				best, bestScore, found = Move{Hold: hold, Placement: p}, score, true
+			}
+		}
+	}
+	try(c, false, depth)
+	if b.UseHold {
+		c := g.Clone()
+		if c.Held != nil {
+			if c.Hold() {
+				try(c, true, depth)
+			}
+		} else if shown > 0 && c.Hold() {
+			// the first shown piece is now falling
+			try(c, true, min(depth, shown-1))
+		}
+	}
+	return best, found
+}
+
+// score places p on a copy of g and rates the result, searching depth more
+// pieces and keeping the best line of play
+func (b *Bot) score(g *model.Game, p model.Placement, depth int) float64 {
+	c := g.Clone()
+	lines := c.Lines
+	c.Place(p)
+	if c.GameOver {
+		return math.Inf(-1)
+	}
+	cleared := float64(c.Lines-lines) * b.Weights.Lines
+	if c.Completed || depth == 0 {
+		return cleared + b.evaluate(c)
+	}
+	best := math.Inf(-1)
+	for _, next := range c.Placements() {
+		best = max(best, b.score(c, next, depth-1))
+	}
+	if math.IsInf(best, -1) {
+		// every follow-up tops out; still prefer the better stack
+		return cleared + b.evaluate(c) + best
+	}
+	return cleared + best
+}
+
+// evaluate rates the stack of g, a clone only the bot is looking at
+func (b *Bot) evaluate(g *model.Game) float64 {
+	rows := len(g.Board)
+	heights := make([]int, g.Width)
+	holes := 0
+	for x := 0; x < g.Width; x++ {
+		for y := 0; y < rows; y++ {
+			if !g.Board[y][x].Filled() {
+				if heights[x] > 0 {
+					holes++
+				}
+				continue
+			}
+			if heights[x] == 0 {
+				heights[x] = rows - y
+			}
+		}
+	}
+
+	height, bumpiness, wells := 0, 0, 0
+	for x, h := range heights {
+		height += h
+		if x > 0 {
+			bumpiness += abs(h - heights[x-1])
+		}
+		// walls count as high neighbours
+		left, right := rows, rows
+		if x > 0 {
+			left = heights[x-1]
+		}
+		if x < len(heights)-1 {
+			right = heights[x+1]
+		}
+		if d := min(left, right) - h; d > 0 {
+			wells += d
+		}
+	}
+
+	w := b.Weights
+	return w.Height*float64(height) + w.Holes*float64(holes) +
+		w.Bumpiness*float64(bumpiness) + w.Wells*float64(wells)
+}
+
+// Play makes moves on g at the bot's pace until the game ends or stop is
+// closed, calling moved after each one. Pausing g pauses the bot.
+func (b *Bot) Play(g *model.Game, stop <-chan struct{}, moved func()) {
+	pps := b.PPS
+	if pps <= 0 {
+		pps = defaultPPS
+	}
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / min(pps, maxPPS)))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		if g.Expire() {
+			moved()
+		}
+		s := g.Snapshot()
+		if s.GameOver || s.Completed {
+			return
+		}
+		if s.Paused {
+			continue
+		}
+		move, ok := b.Best(g)
+		if !ok {
+			continue
+		}
+		if move.Hold {
+			g.Hold()
+		}
+		g.Place(move.Placement)
+		moved()
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,92 @@
+package ai
+
+import (
+	"slices"
+	"testing"
+
+	"tetris-desktop/backend/model"
+)
+
+// testMode is a plain mode on the standard pieces with nothing to wait for
+var testMode = model.GameMode{ID: "test", FallSpeed: 1, PieceSet: "standard", ScoreMultiplier: 1, Hold: true}
+
+// sameStart returns two games whose falling pieces are alike but whose next
+// pieces differ
+func sameStart(t *testing.T, mode model.GameMode) (*model.Game, *model.Game) {
+	t.Helper()
+	a := model.NewSeededGame(mode, 1)
+	nextA, _ := a.Upcoming()
+	for seed := int64(2); seed < 100; seed++ {
+		b := model.NewSeededGame(mode, seed)
+		nextB, _ := b.Upcoming()
+		if b.PieceID == a.PieceID && nextB[0] != nextA[0] {
+			return a, b
+		}
+	}
+	t.Fatal("no seed with the same first piece and another second one")
+	return nil, nil
+}
+
+func TestBestKeepsToShownPreview(t *testing.T) {
+	b := New()
+	b.LookAhead = maxLookAhead
+
+	// without a preview, the hidden next piece must not matter
+	a, c := sameStart(t, testMode)
+	moveA, okA := b.Best(a)
+	moveC, okC := b.Best(c)
+	if !okA || !okC {
+		t.Fatal("no move found")
+	}
+	if moveA.Hold || moveC.Hold {
+		t.Error("held into an empty slot without a preview")
+	}
+	if !slices.Equal(moveA.Placement.Cells, moveC.Placement.Cells) {
+		t.Errorf("moves differ by the hidden piece: %v and %v", moveA.Placement.Cells, moveC.Placement.Cells)
+	}
+	blind := New()
+	blind.LookAhead = 0
+	if move, _ := blind.Best(a); move.Hold || !slices.Equal(move.Placement.Cells, moveA.Placement.Cells) {
+		t.Errorf("searched past the falling piece: %+v, without look-ahead %+v", moveA, move)
+	}
+
+	// with one piece shown, looking further would be the same as looking at it
+	mode := testMode
+	mode.NextPreview = true
+	mode.PreviewCount = 1
+	one := New()
+	one.LookAhead = 1
+	g := model.NewSeededGame(mode, 1)
+	want, _ := one.Best(g)
+	if got, _ := b.Best(g); got.Hold != want.Hold || !slices.Equal(got.Placement.Cells, want.Placement.Cells) {
+		t.Errorf("searched past the preview: %+v, want %+v", got, want)
+	}
+}
+
+func TestLevels(t *testing.T) {
+	seen := map[Weights]string{}
+	lastPPS := 0.0
+	for _, name := range Levels {
+		b, ok := Level(name)
+		if !ok {
+			t.Fatalf("level %q not found", name)
+		}
+		if other, ok := seen[b.Weights]; ok {
+			t.Errorf("levels %q and %q weigh the stack alike", other, name)
+		}
+		seen[b.Weights] = name
+		if b.PPS <= lastPPS {
+			t.Errorf("level %q plays %g pieces per second, not faster than the one before", name, b.PPS)
+		}
+		lastPPS = b.PPS
+
+		// changing a level's bot leaves the level alone
+		b.PPS = 0
+		if again, _ := Level(name); again.PPS == 0 {
+			t.Errorf("level %q changed through a bot", name)
+		}
+	}
+	if _, ok := Level("impossible"); ok {
+		t.Error("unknown level found")
+	}
+}
@@ -0,0 +1,149 @@
+// Package logging sets up the backend's structured logs: a level, text or
+// JSON lines, and optionally a copy in a log file that is rotated by size.
+package logging
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// FileName is the log file kept in the data directory
+const FileName = "tetris.log"
+
+// rotation limits for the log file
+const (
+	maxFileSize = 10 << 20 // bytes before the file is rotated
+	maxBackups  = 3        // rotated files kept, tetris.log.1 being the newest
+)
+
+// Config chooses how the backend logs
+type Config struct {
+	Level slog.Level
+	JSON  bool   // one JSON object per line instead of key=value text
+	File  string // also write to this file, rotating it; "" for stderr only
+}
+
+// Flags registers the logging flags on the command line. The log file is
+// placed in the directory dataDir returns once the flags are parsed.
+func Flags(dataDir func() string) func() Config {
+	level := flag.String("log-level", "info", "log verbosity: debug, info, warn or error")
+	jsonOut := flag.Bool("log-json", false, "log JSON lines instead of text")
+	file := flag.Bool("log-file", false, "also log to "+FileName+" in the data directory, rotated at 10 MB")
+	return func() Config {
+		var cfg Config
+		if err := cfg.Level.UnmarshalText([]byte(*level)); err != nil {
+			fmt.Fprintln(os.Stderr, "Unknown log level", *level, "- using info")
+			cfg.Level = slog.LevelInfo
+		}
+		cfg.JSON = *jsonOut
+		if *file {
+			cfg.File = filepath.Join(dataDir(), FileName)
+		}
+		return cfg
+	}
+}
+
+// Setup makes cfg the default logger, which the standard log package then
+// writes through too. The returned function closes the log file, if any.
+func Setup(cfg Config) (func() error, error) {
+	var out io.Writer = os.Stderr
+	closeFile := func() error { return nil }
+	if cfg.File != "" {
+		f, err := openRotating(cfg.File, maxFileSize, maxBackups)
+		if err != nil {
+			return nil, err
+		}
+		out = io.MultiWriter(os.Stderr, f)
+		closeFile = f.Close
+	}
+	opts := &slog.HandlerOptions{Level: cfg.Level}
+	var h slog.Handler = slog.NewTextHandler(out, opts)
+	if cfg.JSON {
+		h = slog.NewJSONHandler(out, opts)
+	}
+	slog.SetDefault(slog.New(h))
+	return closeFile, nil
+}
+
+// rotatingFile is a log file that is renamed to path.1, path.2, ... once it
+// grows past max bytes, keeping backups old files
+type rotatingFile struct {
+	mu      sync.Mutex
+	path    string
+	max     int64
+	backups int
+	f       *os.File
+	size    int64
+}
+
+func openRotating(path string, max int64, backups int) (*rotatingFile, error) {
+	r := &rotatingFile{path: path, max: max, backups: backups}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *rotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	r.f, r.size = f, info.Size()
+	return nil
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.size+int64(len(p)) > r.max && r.size > 0 {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.f.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// rotate shifts every backup one number up, dropping the oldest, and starts
+// a new file
+func (r *rotatingFile) rotate() error {
+	r.f.Close()
+	for i := r.backups - 1; i >= 1; i-- {
+		os.Rename(r.backup(i), r.backup(i+1))
+	}
+	if r.backups > 0 {
+		os.Rename(r.path, r.backup(1))
+	} else {
+		os.Remove(r.path)
+	}
+	return r.open()
+}
+
+func (r *rotatingFile) backup(i int) string {
+	return fmt.Sprintf("%s.%d", r.path, i)
+}
+
+func (r *rotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.f.Close()
+}
+
+// Fatal logs msg as an error and exits, for failures the backend cannot
+// start without
+func Fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
@@ -1,99 +1,141 @@
 package model
 
-// movement and action methods
+// Movement and action methods. They first end any delay that is over;
+// during a line clear or entry delay there is no piece to move, but
+// rotations and holds are kept for the next piece.
 func (g *Game) MoveLeft() bool {
 	g.mutex.Lock()
 	defer g.mutex.Unlock()
-	if g.GameOver {
+	g.settle()
+	if g.ended() || g.waiting() {
 		return false
 	}
-	if !g.collides(g.X-1, g.Y, g.Piece) {
-		g.X--
-		return true
-	}
-	return false
+	return g.counted(g.shift(-1, 0))
 }
 
 func (g *Game) MoveRight() bool {
 	g.mutex.Lock()
 	defer g.mutex.Unlock()
-	if g.GameOver {
+	g.settle()
+	if g.ended() || g.waiting() {
 		return false
 	}
-	if !g.collides(g.X+1, g.Y, g.Piece) {
-		g.X++
-		return true
-	}
-	return false
+	return g.counted(g.shift(1, 0))
 }
 
 func (g *Game) MoveDown() bool {
 	g.mutex.Lock()
 	defer g.mutex.Unlock()
-	if g.GameOver {
+	g.settle()
+	if g.ended() || g.waiting() {
 		return false
 	}
-	if !g.collides(g.X, g.Y+1, g.Piece) {
-		g.Y++
-		return true
+	// a soft drop only brings the piece down sooner, so finesse leaves it out
+	if !g.shift(0, 1) {
+		return false
 	}
-	return false
+	g.stats.Inputs++
+	return true
 }
 
 func (g *Game) Rotate() bool {
 	g.mutex.Lock()
 	defer g.mutex.Unlock()
-	if g.GameOver {
+	g.settle()
+	if g.ended() {
 		return false
 	}
-	rotated := RotatePiece(g.Piece)
-
-	// Try rotation at current position
-	if !g.collides(g.X, g.Y, rotated) {
-		g.Piece = rotated
-		return true
+	if g.waiting() {
+		// turned as the next piece spawns
+		g.IRS = (g.IRS + 1) % 4
+		return g.counted(true)
 	}
-
-	// Wall kick: try shifting right
-	if !g.collides(g.X+1, g.Y, rotated) {
-		g.X++
-		g.Piece = rotated
-		return true
+	x, o, ok := g.rotation(g.X, g.Y, g.Orientation)
+	if !ok {
+		return false
 	}
+	g.X = x
+	g.Orientation = o
+	g.Piece = g.def().orientation(o)
+	g.rotated = true
+	return g.counted(true)
+}
 
-	// Wall kick: try shifting left
-	if !g.collides(g.X-1, g.Y, rotated) {
-		g.X--
-		g.Piece = rotated
-		return true
+// AutoRepeat moves the piece one cell left, right or down as a held key
+// repeats. Unlike MoveLeft, MoveRight and MoveDown it is not a new input.
+func (g *Game) AutoRepeat(dir string) bool {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.settle()
+	if g.ended() || g.waiting() {
+		return false
+	}
+	switch dir {
+	case InputLeft:
+		return g.shift(-1, 0)
+	case InputRight:
+		return g.shift(1, 0)
+	case InputDown:
+		return g.shift(0, 1)
 	}
+	return false
+}
 
-	// Wall kick: try shifting right by 2 (for I-piece)
-	if !g.collides(g.X+2, g.Y, rotated) {
-		g.X += 2
-		g.Piece = rotated
-		return true
+// shift moves the falling piece by dx, dy if it fits there
+func (g *Game) shift(dx, dy int) bool {
+	if g.collides(g.X+dx, g.Y+dy, g.Piece) {
+		return false
+	}
+	g.X += dx
+	g.Y += dy
+	if dy > 0 {
+		g.grounded = false
 	}
+	g.rotated = false
+	return true
+}
 
-	// Wall kick: try shifting left by 2 (for I-piece)
-	if !g.collides(g.X-2, g.Y, rotated) {
-		g.X -= 2
-		g.Piece = rotated
-		return true
+// counted notes an input that moved or turned the piece, for finesse
+func (g *Game) counted(ok bool) bool {
+	if ok {
+		g.stats.Inputs++
+		g.pieceInputs++
 	}
+	return ok
+}
 
-	return false
+// rotation returns the column and orientation the falling piece ends up in
+// when rotated clockwise from x, y, o, or false if it cannot rotate there
+func (g *Game) rotation(x, y, o int) (int, int, bool) {
+	next := (o + 1) % 4
+	rotated := g.def().rotations[next]
+
+	// Try rotation at current position; classic rotation has no wall
+	// kicks, otherwise try shifting right, left, then by 2 (for I-piece)
+	kicks := []int{0}
+	if g.Mode.Rotation != RotationClassic {
+		kicks = []int{0, 1, -1, 2, -2}
+	}
+	for _, dx := range kicks {
+		if !g.collides(x+dx, y, rotated) {
+			return x + dx, next, true
+		}
+	}
+	return 0, 0, false
 }
 
 func (g *Game) Drop() bool {
 	g.mutex.Lock()
 	defer g.mutex.Unlock()
-	if g.GameOver {
+	g.settle()
+	if g.ended() || g.waiting() {
 		return false
 	}
 	for !g.collides(g.X, g.Y+1, g.Piece) {
 		g.Y++
+		g.rotated = false
 	}
+	g.stats.Inputs++
 	g.lock()
 	return true
 }
@@ -105,5 +147,10 @@ func (g *Game) TogglePause() bool {
 		return false
 	}
 	g.Paused = !g.Paused
+	if g.Paused {
+		g.clock.stop()
+	} else if !g.ended() {
+		g.clock.start()
+	}
 	return true
 }
@@ -1,109 +1,35 @@
 package model
 
-// Define simple tetrominoes as 4x4 matrices
-var Tetrominoes = [][][]int{
-	// I
-	{
-		{0, 0, 0, 0},
-		{1, 1, 1, 1},
-		{0, 0, 0, 0},
-		{0, 0, 0, 0},
-	},
-	// O
-	{
-		{0, 0, 0, 0},
-		{0, 2, 2, 0},
-		{0, 2, 2, 0},
-		{0, 0, 0, 0},
-	},
-	// T
-	{
-		{0, 0, 0, 0},
-		{3, 3, 3, 0},
-		{0, 3, 0, 0},
-		{0, 0, 0, 0},
-	},
-	// S
-	{
-		{0, 0, 0, 0},
-		{0, 4, 4, 0},
-		{4, 4, 0, 0},
-		{0, 0, 0, 0},
-	},
-	// Z
-	{
-		{0, 0, 0, 0},
-		{5, 5, 0, 0},
-		{0, 5, 5, 0},
-		{0, 0, 0, 0},
-	},
-	// J
-	{
-		{0, 0, 0, 0},
-		{6, 6, 6, 0},
-		{0, 0, 6, 0},
-		{0, 0, 0, 0},
-	},
-	// L
-	{
-		{0, 0, 0, 0},
-		{7, 7, 7, 0},
-		{7, 0, 0, 0},
-		{0, 0, 0, 0},
-	},
-	// i
-	{
-		{0, 0, 0, 0},
-		{8, 8, 8, 0},
-		{0, 0, 0, 0},
-		{0, 0, 0, 0},
-	},
-	// l
-	{
-		{0, 0, 0, 0},
-		{0, 9, 0, 0},
-		{0, 9, 9, 0},
-		{0, 0, 0, 0},
-	},
-	// C
-	{
-		{0, 0, 0, 0},
-		{10, 10, 10, 0},
-		{10, 0, 10, 0},
-		{0, 0, 0, 0},
-	},
-	// \\ (diagonal example)
-	{
-		{0, 0, 0, 0},
-		{11, 0, 0, 0},
-		{0, 11, 0, 0},
-		{0, 0, 11, 0},
-	},
-	// Y
-	{
-		{0, 0, 0, 0},
-		{12, 0, 12, 0},
-		{0, 12, 0, 0},
-		{0, 0, 0, 0},
-	},
-}
-
+// Flatten turns a square piece matrix into a row-major slice
 func Flatten(mat [][]int) []int {
-	out := make([]int, 16)
-	for y := 0; y < 4; y++ {
-		for x := 0; x < 4; x++ {
-			out[y*4+x] = mat[y][x]
+	n := len(mat)
+	out := make([]int, n*n)
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			out[y*n+x] = mat[y][x]
 		}
 	}
 	return out
 }
 
+// RotatePiece rotates a flattened square piece clockwise about the centre
+// of its box
 func RotatePiece(piece []int) []int {
-	out := make([]int, 16)
-	for y := 0; y < 4; y++ {
-		for x := 0; x < 4; x++ {
-			out[x*4+(3-y)] = piece[y*4+x]
+	n := pieceSize(piece)
+	out := make([]int, n*n)
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			out[x*n+(n-1-y)] = piece[y*n+x]
 		}
 	}
 	return out
 }
+
+// pieceSize returns the side of a flattened square piece
+func pieceSize(p []int) int {
+	n := 0
+	for n*n < len(p) {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,87 @@
+package model
+
+// board size limits a mode may ask for
+const (
+	minWidth  = 4
+	maxWidth  = 40
+	minHeight = 4
+	maxHeight = 60
+	minBuffer = 4 // room for a whole piece to spawn above the field
+	maxBuffer = 40
+)
+
+// width returns the number of columns
+func (m GameMode) width() int {
+	if m.Width > 0 {
+		return m.Width
+	}
+	return Cols
+}
+
+// height returns the number of visible rows
+func (m GameMode) height() int {
+	if m.Height > 0 {
+		return m.Height
+	}
+	return Rows
+}
+
+// buffer returns the number of hidden rows above the visible field
+func (m GameMode) buffer() int {
+	if m.Buffer > 0 {
+		return m.Buffer
+	}
+	return Buffer
+}
+
+// newBoard returns an empty board of rows x cols
+func newBoard(rows, cols int) [][]Cell {
+	b := make([][]Cell, rows)
+	for i := range b {
+		b[i] = make([]Cell, cols)
+	}
+	return b
+}
+
+// rows returns the height of the board including the hidden buffer
+func (g *Game) rows() int {
+	return len(g.Board)
+}
+
+// placeAtSpawn centres the falling piece's box with its lowest blocks on the
+// row just above the visible field, then drops it one row into view if
+// nothing is in the way
+func (g *Game) placeAtSpawn() {
+	g.X = (g.Width - pieceSize(g.Piece)) / 2
+	g.Y = g.Hidden - 1 - lowestRow(g.Piece)
+	if !g.collides(g.X, g.Y+1, g.Piece) {
+		g.Y++
+	}
+	g.grounded = false
+	g.rotated = false
+	g.spawnPose = pose{g.X, g.Y, g.Orientation}
+}
+
+// lowestRow returns the bottom filled row of a flattened piece
+func lowestRow(p []int) int {
+	n := pieceSize(p)
+	low := 0
+	for i, v := range p {
+		if v != 0 {
+			low = i / n
+		}
+	}
+	return low
+}
+
+// aboveField reports whether every block of the piece at px, py is in the
+// hidden buffer
+func (g *Game) aboveField(px, py int, p []int) bool {
+	n := pieceSize(p)
+	for i, v := range p {
+		if v != 0 && py+i/n >= g.Hidden {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,71 @@
+package model
+
+import "encoding/json"
+
+// Origin tells how a board cell came to be filled
+type Origin uint8
+
+const (
+	OriginEmpty   Origin = iota
+	OriginPlaced         // locked from a falling piece
+	OriginGarbage        // generated garbage
+	OriginItem           // special block
+)
+
+// Cell is one square of the board. On the wire it is a single number:
+// colour in the low byte, then kind, origin and metadata, so an empty cell
+// is 0 and any filled cell is nonzero.
+type Cell struct {
+	Color  uint8 // palette index the cell is drawn with
+	Kind   uint8 // 1-based index of the piece in its set; 0 for garbage
+	Origin Origin
+	Meta   uint8 // free for items and other special blocks
+}
+
+// Filled reports whether the cell holds a block
+func (c Cell) Filled() bool {
+	return c.Origin != OriginEmpty
+}
+
+// Pack returns the compact numeric form of the cell
+func (c Cell) Pack() uint32 {
+	return uint32(c.Color) | uint32(c.Kind)<<8 | uint32(c.Origin)<<16 | uint32(c.Meta)<<24
+}
+
+// UnpackCell is the inverse of Pack
+func UnpackCell(v uint32) Cell {
+	return Cell{
+		Color:  uint8(v),
+		Kind:   uint8(v >> 8),
+		Origin: Origin(v >> 16),
+		Meta:   uint8(v >> 24),
+	}
+}
+
+// MarshalJSON encodes the cell in its packed form
+func (c Cell) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.Pack())
+}
+
+// UnmarshalJSON decodes a packed cell
+func (c *Cell) UnmarshalJSON(data []byte) error {
+	var v uint32
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*c = UnpackCell(v)
+	return nil
+}
+
+// ColorGrid returns the board as colour values only, the format the board
+// was sent in before cells carried piece identity
+func (g *GameState) ColorGrid() [][]int {
+	out := make([][]int, len(g.Board))
+	for y, row := range g.Board {
+		out[y] = make([]int, len(row))
+		for x, c := range row {
+			out[y][x] = int(c.Color)
+		}
+	}
+	return out
+}
@@ -0,0 +1,34 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestCellPacking(t *testing.T) {
+	tests := []struct {
+		cell Cell
+		want uint32
+	}{
+		{Cell{}, 0},
+		{Cell{Color: 3, Kind: 3, Origin: OriginPlaced}, 0x010303},
+		{Cell{Color: GarbageCell, Origin: OriginGarbage}, 0x02000d},
+		{Cell{Color: 255, Kind: 255, Origin: OriginItem, Meta: 255}, 0xff03ffff},
+	}
+	for _, tt := range tests {
+		if got := tt.cell.Pack(); got != tt.want {
+			t.Errorf("%+v packs to %#x, want %#x", tt.cell, got, tt.want)
+		}
+		if got := UnpackCell(tt.want); got != tt.cell {
+			t.Errorf("%#x unpacks to %+v, want %+v", tt.want, got, tt.cell)
+		}
+		data, err := json.Marshal(tt.cell)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var back Cell
+		if err := json.Unmarshal(data, &back); err != nil || back != tt.cell {
+			t.Errorf("%s decodes to %+v (%v), want %+v", data, back, err, tt.cell)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package model
+
+import (
+	"math/rand"
+	"slices"
+)
+
+// Clone returns an independent copy of the game, unexported state included,
+// for trying moves without touching the game being played. The copy draws
+// from a fresh random source, so it cannot see pieces beyond the preview.
+func (g *Game) Clone() *Game {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	c := &Game{
+		Board:       make([][]Cell, len(g.Board)),
+		Width:       g.Width,
+		Height:      g.Height,
+		Hidden:      g.Hidden,
+		Piece:       slices.Clone(g.Piece),
+		Next:        make([][]int, len(g.Next)),
+		PieceID:     g.PieceID,
+		Orientation: g.Orientation,
+		X:           g.X,
+		Y:           g.Y,
+		Score:       g.Score,
+		Lines:       g.Lines,
+		Level:       g.Level,
+		Pieces:      g.Pieces,
+		GarbageLeft: g.GarbageLeft,
+		Elapsed:     g.Elapsed,
+		GameOver:    g.GameOver,
+		TopOut:      g.TopOut,
+		Completed:   g.Completed,
+		Paused:      g.Paused,
+		Held:        slices.Clone(g.Held),
+		HoldUsed:    g.HoldUsed,
+		Clearing:    slices.Clone(g.Clearing),
+		IRS:         g.IRS,
+		IHS:         g.IHS,
+		Finesse:     g.Finesse,
+		HighScore:   g.HighScore,
+		Seed:        g.Seed,
+		Mode:        g.Mode,
+		clock:       g.clock,
+		rng:         rand.New(rand.NewSource(rand.Int63())),
+		garbageSent: g.garbageSent,
+		holes:       slices.Clone(g.holes),
+		nextRise:    g.nextRise,
+		pieces:      g.pieces,
+		queue:       slices.Clone(g.queue),
+		held:        g.held,
+		bag:         slices.Clone(g.bag),
+		groundedAt:  g.groundedAt,
+		grounded:    g.grounded,
+		waitUntil:   g.waitUntil,
+		stats:       g.statsNow(),
+		combo:       g.combo,
+		pieceInputs: g.pieceInputs,
+		spawnPose:   g.spawnPose,
+		rotated:     g.rotated,
+	}
+	for i := range g.Board {
+		c.Board[i] = slices.Clone(g.Board[i])
+	}
+	for i := range g.Next {
+		c.Next[i] = slices.Clone(g.Next[i])
+	}
+	return c
+}
@@ -2,18 +2,19 @@ package model
 
 // internal collision check
 func (g *Game) collides(px, py int, p []int) bool {
-	for y := 0; y < 4; y++ {
-		for x := 0; x < 4; x++ {
-			v := p[y*4+x]
+	n := pieceSize(p)
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			v := p[y*n+x]
 			if v == 0 {
 				continue
 			}
 			bx := px + x
 			by := py + y
-			if bx < 0 || bx >= Cols || by < 0 || by >= Rows {
+			if bx < 0 || bx >= g.Width || by < 0 || by >= g.rows() {
 				return true
 			}
-			if g.Board[by][bx] != 0 {
+			if g.Board[by][bx].Filled() {
 				return true
 			}
 		}
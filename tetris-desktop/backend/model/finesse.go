@@ -0,0 +1,141 @@
+package model
+
+// Key presses of a finesse path. A tap moves or turns the piece once; the
+// held ones keep going until the piece stops.
+const (
+	FinesseLeft     = InputLeft
+	FinesseRight    = InputRight
+	FinesseRotate   = InputRotate
+	FinesseDASLeft  = "dasLeft"  // held until the piece stops at the left wall
+	FinesseDASRight = "dasRight" // held until the piece stops at the right wall
+	FinesseSoftDrop = "softDrop" // held until the piece lands
+)
+
+// FinesseCheck judges how a piece was brought to where it locked
+type FinesseCheck struct {
+	Number int      `json:"number"` // pieces judged so far this game, retries included
+	Piece  string   `json:"piece"`
+	Inputs int      `json:"inputs"` // presses the player used, soft drops aside
+	Least  int      `json:"least"`  // fewest presses that reach the same spot, soft drops aside
+	Path   []string `json:"path"`   // one way of doing it in Least presses and any soft drops it needs
+	Fault  bool     `json:"fault"`
+	Retry  bool     `json:"retry,omitempty"` // training put the piece back at the top instead of locking it
+}
+
+// finesse works out the fewest key presses that bring the falling piece
+// from start to where a hard drop leaves it filling target, the cellsKey
+// of a placement. A key held until the piece stops at a wall, or soft
+// dropped to the floor, is one press. Gravity and the drop itself are not
+// counted. ok is false if the piece cannot get there.
+func (g *Game) finesse(start pose, target string) (path []string, ok bool) {
+	steps := []step{{pose: start, parent: -1}}
+	visited := map[pose]bool{start: true}
+	for i := 0; i < len(steps); i++ {
+		cur := steps[i].pose
+		piece := g.def().rotations[cur.o]
+		if cellsKey(g.cells(g.landing(cur), piece)) == target {
+			return pathTo(steps, i), true
+		}
+		next := func(p pose, input string) {
+			if !visited[p] {
+				visited[p] = true
+				steps = append(steps, step{p, i, input})
+			}
+		}
+		for _, dx := range []int{-1, 1} {
+			if g.collides(cur.x+dx, cur.y, piece) {
+				continue
+			}
+			tap, das := FinesseLeft, FinesseDASLeft
+			if dx > 0 {
+				tap, das = FinesseRight, FinesseDASRight
+			}
+			next(pose{cur.x + dx, cur.y, cur.o}, tap)
+			slid := cur
+			for !g.collides(slid.x+dx, slid.y, piece) {
+				slid.x += dx
+			}
+			next(slid, das)
+		}
+		if x, o, ok := g.rotation(cur.x, cur.y, cur.o); ok {
+			next(pose{x, cur.y, o}, FinesseRotate)
+		}
+		if land := g.landing(cur); land != cur {
+			next(land, FinesseSoftDrop)
+		}
+	}
+	return nil, false
+}
+
+// landing returns where a hard drop from p leaves the falling piece
+func (g *Game) landing(p pose) pose {
+	piece := g.def().rotations[p.o]
+	for !g.collides(p.x, p.y+1, piece) {
+		p.y++
+	}
+	return p
+}
+
+// judgeFinesse compares the presses that brought the falling piece to where
+// it is about to lock with the fewest that would have, counting a fault
+// when there were more. It reports whether there was a fault. Whole
+// placements have no presses to judge.
+func (g *Game) judgeFinesse() bool {
+	if g.placing {
+		return false
+	}
+	cells := g.cells(pose{g.X, g.Y, g.Orientation}, g.Piece)
+	path, ok := g.finesse(g.spawnPose, cellsKey(cells))
+	if !ok {
+		return false
+	}
+	if path == nil {
+		path = []string{}
+	}
+	// soft drops are not counted on either side; the player's only hurry
+	// the piece down, and the path's tuck it under an overhang
+	least := 0
+	for _, in := range path {
+		if in != FinesseSoftDrop {
+			least++
+		}
+	}
+	name := g.def().Name
+	check := &FinesseCheck{
+		Piece:  name,
+		Inputs: g.pieceInputs,
+		Least:  least,
+		Path:   path,
+		Fault:  g.pieceInputs > least,
+	}
+	if g.Finesse != nil {
+		check.Number = g.Finesse.Number
+	}
+	check.Number++
+	g.Finesse = check
+	if !check.Fault {
+		return false
+	}
+	g.stats.FinesseFaults++
+	if g.stats.FinesseByType == nil {
+		g.stats.FinesseByType = map[string]int{}
+	}
+	g.stats.FinesseByType[name]++
+	return true
+}
+
+// retryPiece puts the falling piece back where it entered, for another go
+// at placing it in training. It reports false if the way back is blocked.
+func (g *Game) retryPiece() bool {
+	piece := g.def().orientation(g.spawnPose.o)
+	if g.collides(g.spawnPose.x, g.spawnPose.y, piece) {
+		return false
+	}
+	g.X, g.Y, g.Orientation = g.spawnPose.x, g.spawnPose.y, g.spawnPose.o
+	g.Piece = piece
+	g.grounded = false
+	g.rotated = false
+	g.pieceInputs = 0
+	g.Finesse.Retry = true
+	return true
+}
@@ -0,0 +1,124 @@
+package model
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestJudgeFinesse(t *testing.T) {
+	left := func(g *Game) bool { return g.MoveLeft() }
+	right := func(g *Game) bool { return g.MoveRight() }
+	rotate := func(g *Game) bool { return g.Rotate() }
+	down := func(g *Game) bool { return g.MoveDown() }
+	tests := []struct {
+		name   string
+		inputs []func(*Game) bool
+		toWall bool // keep tapping left until the piece stops
+		inputN int
+		path   []string
+		fault  bool
+	}{
+		{name: "straight drop", path: []string{}},
+		{name: "one tap", inputs: []func(*Game) bool{left}, inputN: 1, path: []string{FinesseLeft}},
+		{name: "left right left", inputs: []func(*Game) bool{left, right, left}, inputN: 3, path: []string{FinesseLeft}, fault: true},
+		{name: "full turn", inputs: []func(*Game) bool{rotate, rotate, rotate, rotate}, inputN: 4, path: []string{}, fault: true},
+		{name: "soft drop", inputs: []func(*Game) bool{down, down}, path: []string{}},
+		{name: "soft drop around a tap", inputs: []func(*Game) bool{down, left, down}, inputN: 1, path: []string{FinesseLeft}},
+		{name: "taps to the wall", toWall: true, inputN: 3, path: []string{FinesseDASLeft}, fault: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newTestGame(t, testMode, "T")
+			for _, in := range tt.inputs {
+				if !in(g) {
+					t.Fatal("input did nothing")
+				}
+			}
+			for tt.toWall && g.MoveLeft() {
+			}
+			g.Drop()
+			f := g.Finesse
+			if f == nil {
+				t.Fatal("piece not judged")
+			}
+			if f.Piece != "T" || f.Inputs != tt.inputN || f.Least != len(tt.path) || f.Fault != tt.fault || f.Number != 1 {
+				t.Errorf("got %+v, want %d inputs, least %d, fault %v", *f, tt.inputN, len(tt.path), tt.fault)
+			}
+			if !slices.Equal(f.Path, tt.path) || f.Path == nil {
+				t.Errorf("path %q, want %q", f.Path, tt.path)
+			}
+			stats := g.Stats()
+			faults := 0
+			if tt.fault {
+				faults = 1
+			}
+			if stats.FinesseFaults != faults || stats.FinesseByType["T"] != faults {
+				t.Errorf("stats count %d faults, %d for T, want %d", stats.FinesseFaults, stats.FinesseByType["T"], faults)
+			}
+		})
+	}
+}
+
+func TestFinesseTuck(t *testing.T) {
+	g := newTestGame(t, testMode, "T")
+	for g.MoveDown() {
+	}
+	// a roof over the two columns left of the landed piece, so only a soft
+	// drop under it reaches them
+	cells := g.cells(pose{g.X, g.Y, g.Orientation}, g.Piece)
+	left, top := cells[0].X, cells[0].Y
+	for _, c := range cells {
+		left, top = min(left, c.X), min(top, c.Y)
+	}
+	for x := left - 2; x < left; x++ {
+		g.Board[top-1][x] = Cell{Color: 1, Kind: 1, Origin: OriginPlaced}
+	}
+	g.MoveLeft()
+	g.MoveLeft()
+	g.Drop()
+
+	f := g.Finesse
+	if f == nil {
+		t.Fatal("piece not judged")
+	}
+	if f.Inputs != 2 || f.Least != 2 || f.Fault || !slices.Contains(f.Path, FinesseSoftDrop) {
+		t.Errorf("got %+v, want 2 inputs as least, with a soft drop on the path", *f)
+	}
+}
+
+func TestFinesseTraining(t *testing.T) {
+	mode := testMode
+	mode.FinesseTraining = true
+	g := newTestGame(t, mode, "T")
+	start := pose{g.X, g.Y, g.Orientation}
+	g.MoveLeft()
+	g.MoveRight()
+	g.MoveLeft()
+	g.Drop()
+	if g.Pieces != 0 || g.PieceID == 0 || g.def().Name != "T" {
+		t.Fatalf("faulty piece locked: %d pieces, falling %d", g.Pieces, g.PieceID)
+	}
+	if got := (pose{g.X, g.Y, g.Orientation}); got != start {
+		t.Errorf("piece back at %v, want %v", got, start)
+	}
+	if !g.Finesse.Retry || !g.Finesse.Fault {
+		t.Errorf("got %+v, want a retried fault", *g.Finesse)
+	}
+
+	// placed cleanly the second time, it locks
+	g.MoveLeft()
+	g.Drop()
+	if g.Pieces != 1 || g.Finesse.Fault || g.Finesse.Number != 2 {
+		t.Errorf("clean retry: %d pieces, finesse %+v", g.Pieces, *g.Finesse)
+	}
+}
+
+func TestPlaceNotJudged(t *testing.T) {
+	g := newTestGame(t, testMode, "T")
+	if !g.Place(g.Placements()[0]) {
+		t.Fatal("placement refused")
+	}
+	if g.Finesse != nil || g.Stats().FinesseFaults != 0 {
+		t.Errorf("whole placement judged: %+v", g.Finesse)
+	}
+}
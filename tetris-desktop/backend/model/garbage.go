@@ -0,0 +1,111 @@
+package model
+
+import (
+	"slices"
+	"time"
+)
+
+// GarbageCell is the colour of a generated garbage block
+const GarbageCell = 13
+
+// holes per garbage row when the mode doesn't say
+const defaultHoles = 1
+
+// Garbage configures generated garbage for dig modes.
+// A zero Garbage means the board starts empty.
+type Garbage struct {
+	Rows         int     `json:"rows,omitempty"`         // rows on the board at the start
+	Total        int     `json:"total,omitempty"`        // rows in the whole race; defaults to Rows
+	Holes        int     `json:"holes,omitempty"`        // holes per row; defaults to 1
+	Messiness    float64 `json:"messiness,omitempty"`    // chance, 0..1, that a row's holes move away from the row below
+	RisePieces   int     `json:"risePieces,omitempty"`   // pieces placed between rising rows; 0 disables
+	RiseInterval int64   `json:"riseInterval,omitempty"` // milliseconds between rising rows; 0 disables
+}
+
+// total returns the number of garbage rows in the whole game
+func (gr Garbage) total() int {
+	return max(gr.Total, gr.Rows)
+}
+
+// fillGarbage puts the mode's starting garbage at the bottom of the board
+func (g *Game) fillGarbage() {
+	g.GarbageLeft = g.Mode.Garbage.total()
+	for i := 0; i < min(g.Mode.Garbage.Rows, g.Height-1); i++ {
+		g.raiseGarbage()
+	}
+	if g.Mode.Garbage.RiseInterval > 0 {
+		g.nextRise = time.Duration(g.Mode.Garbage.RiseInterval) * time.Millisecond
+	}
+}
+
+// garbageRow generates the next row of garbage, moving its holes with the
+// mode's messiness
+func (g *Game) garbageRow() []Cell {
+	holes := g.Mode.Garbage.Holes
+	if holes <= 0 {
+		holes = defaultHoles
+	}
+	holes = min(holes, g.Width-1)
+	if len(g.holes) != holes || g.rng.Float64() < g.Mode.Garbage.Messiness {
+		g.holes = g.rng.Perm(g.Width)[:holes]
+	}
+	row := make([]Cell, g.Width)
+	for x := range row {
+		row[x] = Cell{Color: GarbageCell, Origin: OriginGarbage}
+	}
+	for _, x := range g.holes {
+		row[x] = Cell{}
+	}
+	return row
+}
+
+// raiseGarbage pushes the stack up by one row of garbage, if any is left to send.
+// The falling piece is lifted with it, and the game tops out if blocks are pushed
+// off the top of the board.
+func (g *Game) raiseGarbage() {
+	if g.garbageSent >= g.Mode.Garbage.total() {
+		return
+	}
+	if slices.ContainsFunc(g.Board[0], Cell.Filled) {
+		g.topOut(PushOut)
+		return
+	}
+	g.garbageSent++
+	g.Board = append(g.Board[1:], g.garbageRow())
+	for i := range g.Clearing {
+		g.Clearing[i]--
+	}
+	if g.Piece != nil && g.collides(g.X, g.Y, g.Piece) {
+		g.Y--
+	}
+}
+
+// riseForPiece raises a row every RisePieces pieces placed
+func (g *Game) riseForPiece() {
+	n := g.Mode.Garbage.RisePieces
+	if n > 0 && g.Pieces%n == 0 {
+		g.raiseGarbage()
+	}
+}
+
+// riseForTime raises a row for every RiseInterval of play time that has passed
+func (g *Game) riseForTime() {
+	if g.nextRise <= 0 {
+		return
+	}
+	interval := time.Duration(g.Mode.Garbage.RiseInterval) * time.Millisecond
+	for g.clock.elapsed() >= g.nextRise && !g.ended() {
+		g.raiseGarbage()
+		g.nextRise += interval
+	}
+}
+
+// isGarbageRow reports whether a row still holds garbage blocks
+func isGarbageRow(row []Cell) bool {
+	for _, c := range row {
+		if c.Origin == OriginGarbage {
+			return true
+		}
+	}
+	return false
+}
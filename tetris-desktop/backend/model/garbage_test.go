@@ -0,0 +1,45 @@
+package model
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRaiseGarbage(t *testing.T) {
+	tests := []struct {
+		name    string
+		topRow  bool // a block in the board's top row
+		total   int
+		sent    int
+		gameOut bool
+	}{
+		{name: "raises a row", total: 2, sent: 1},
+		{name: "nothing left to send", total: 0, sent: 0},
+		{name: "pushes blocks off the top", topRow: true, total: 2, sent: 0, gameOut: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mode := testMode
+			mode.Garbage.Total = tt.total
+			g := newTestGame(t, mode, "O")
+			if tt.topRow {
+				g.Board[0][0] = Cell{Color: 1, Kind: 1, Origin: OriginPlaced}
+			}
+			before := slices.Clone(g.Board[g.rows()-1])
+			g.raiseGarbage()
+			if g.garbageSent != tt.sent {
+				t.Errorf("sent %d rows, want %d", g.garbageSent, tt.sent)
+			}
+			if tt.gameOut != (g.TopOut == PushOut) || g.GameOver != tt.gameOut {
+				t.Errorf("game over %v (%q), want %v", g.GameOver, g.TopOut, tt.gameOut)
+			}
+			bottom := g.Board[g.rows()-1]
+			if raised := isGarbageRow(bottom); raised != (tt.sent > 0) {
+				t.Errorf("bottom row is garbage: %v", raised)
+			}
+			if tt.sent == 0 && !slices.Equal(bottom, before) {
+				t.Error("board changed without raising")
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package model
+
+import "time"
+
+// goalReached reports whether the mode's line or garbage goal has been met
+func (g *Game) goalReached() bool {
+	if g.Mode.Goal.Garbage && g.GarbageLeft <= 0 {
+		return true
+	}
+	return g.Mode.Goal.Lines > 0 && g.Lines >= g.Mode.Goal.Lines
+}
+
+// timeUp reports whether a timed mode has run out of time
+func (g *Game) timeUp() bool {
+	limit := g.Mode.Goal.TimeLimit
+	return limit > 0 && g.clock.elapsed().Milliseconds() >= limit
+}
+
+// Endless reports whether the mode has no goal or time limit, so its games
+// only end by topping out
+func (m GameMode) Endless() bool {
+	return m.Goal == Goal{}
+}
+
+// ended reports whether the game is over, by topping out or by completing it
+func (g *Game) ended() bool {
+	return g.GameOver || g.Completed
+}
+
+// topOut ends the game because the stack reached the top, recording why
+func (g *Game) topOut(cause string) {
+	g.GameOver = true
+	g.TopOut = cause
+	g.clock.stop()
+}
+
+// complete ends the game because its goal was met
+func (g *Game) complete() {
+	g.Completed = true
+	g.clock.stop()
+}
+
+// Remaining returns the play time left in a timed mode, or 0 if the mode has no limit
+func (g *Game) Remaining() time.Duration {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if g.Mode.Goal.TimeLimit <= 0 || g.ended() {
+		return 0
+	}
+	left := time.Duration(g.Mode.Goal.TimeLimit)*time.Millisecond - g.clock.elapsed()
+	if left < time.Millisecond {
+		left = time.Millisecond
+	}
+	return left
+}
+
+// Running reports whether the game is neither over nor paused
+func (g *Game) Running() bool {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return !g.ended() && !g.Paused
+}
+
+// TimeUp reports whether a timed game that has not ended yet has run out
+// of time, so Expire would end it
+func (g *Game) TimeUp() bool {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return !g.ended() && g.timeUp()
+}
+
+// Expire completes a timed game once its limit has passed.
+// It reports whether the game ended.
+func (g *Game) Expire() bool {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if g.ended() || !g.timeUp() {
+		return false
+	}
+	g.complete()
+	return true
+}
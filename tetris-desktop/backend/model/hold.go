@@ -0,0 +1,39 @@
+package model
+
+// Hold swaps the falling piece with the held one, or stores it and takes the
+// next piece when the hold slot is empty. It can be used once per piece.
+func (g *Game) Hold() bool {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if g.ended() || g.Paused || !g.Mode.Hold || g.HoldUsed {
+		return false
+	}
+	g.settle()
+	g.stats.Inputs++
+	if g.waiting() {
+		// held as the next piece spawns
+		g.IHS = !g.IHS
+		return true
+	}
+	g.hold()
+	return true
+}
+
+// hold swaps the falling piece into the hold slot
+func (g *Game) hold() {
+	// hold the piece in its spawn orientation
+	current := g.PieceID - 1
+	if g.Held == nil {
+		g.spawn()
+	} else {
+		g.setPiece(g.held)
+		g.placeAtSpawn()
+	}
+	g.held = current
+	g.Held = g.pieces.Pieces[current].orientation(0)
+	g.HoldUsed = true
+	g.pieceInputs = 0
+	if g.collides(g.X, g.Y, g.Piece) {
+		g.topOut(BlockOut)
+	}
+}
@@ -1,56 +1,83 @@
 package model
 
+import "slices"
+
 // internal lock
 func (g *Game) lock() {
-	for y := 0; y < 4; y++ {
-		for x := 0; x < 4; x++ {
-			v := g.Piece[y*4+x]
+	if g.judgeFinesse() && g.Mode.FinesseTraining && g.retryPiece() {
+		return
+	}
+	covered := g.coveredCells()
+	g.countPiece()
+	n := pieceSize(g.Piece)
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			v := g.Piece[y*n+x]
 			if v == 0 {
 				continue
 			}
 			bx := g.X + x
 			by := g.Y + y
-			if by >= 0 && by < Rows && bx >= 0 && bx < Cols {
-				g.Board[by][bx] = v
+			if by >= 0 && by < g.rows() && bx >= 0 && bx < g.Width {
+				g.Board[by][bx] = Cell{Color: uint8(v), Kind: uint8(g.PieceID), Origin: OriginPlaced}
 			}
 		}
 	}
-	g.clearLines()
-	g.spawn()
-	if g.collides(g.X, g.Y, g.Piece) {
-		g.GameOver = true
+	g.Pieces++
+	g.stats.Holes += max(g.coveredCells()-covered, 0)
+	if g.aboveField(g.X, g.Y, g.Piece) {
+		g.topOut(LockOut)
+		return
+	}
+	g.HoldUsed = false
+	g.afterLock(g.clock.elapsed())
+}
+
+// fullRows returns the indexes of the rows with every cell filled
+func (g *Game) fullRows() []int {
+	var rows []int
+	for y := 0; y < g.rows(); y++ {
+		if !slices.ContainsFunc(g.Board[y], func(c Cell) bool { return !c.Filled() }) {
+			rows = append(rows, y)
+		}
 	}
+	return rows
 }
 
 // clear completed lines and update score
 func (g *Game) clearLines() {
-	newBoard := make([][]int, 0, Rows)
+	newBoard := make([][]Cell, 0, g.rows())
 	cleared := 0
-	for y := 0; y < Rows; y++ {
+	for y := 0; y < g.rows(); y++ {
 		full := true
-		for x := 0; x < Cols; x++ {
-			if g.Board[y][x] == 0 {
+		for x := 0; x < g.Width; x++ {
+			if !g.Board[y][x].Filled() {
 				full = false
 				break
 			}
 		}
 		if !full {
-			rowCopy := make([]int, Cols)
+			rowCopy := make([]Cell, g.Width)
 			copy(rowCopy, g.Board[y])
 			newBoard = append(newBoard, rowCopy)
 		} else {
 			cleared++
+			if isGarbageRow(g.Board[y]) {
+				g.GarbageLeft--
+			}
 		}
 	}
 	for i := 0; i < cleared; i++ {
-		newRow := make([]int, Cols)
-		newBoard = append([][]int{newRow}, newBoard...)
+		newRow := make([]Cell, g.Width)
+		newBoard = append([][]Cell{newRow}, newBoard...)
 	}
 	g.Board = newBoard
+	g.countClear(cleared)
+	g.Lines += cleared
+	if g.Mode.LevelLines > 0 {
+		g.Level = 1 + g.Lines/g.Mode.LevelLines
+	}
 	if cleared > 0 {
-		baseScore := cleared * 100
-		lineMultiplier := cleared
-		total := int(float64(baseScore*lineMultiplier) * g.Mode.ScoreMultiplier)
-		g.Score += total
+		g.Score += g.Mode.lineScore(cleared)
 	}
 }
@@ -0,0 +1,150 @@
+package model
+
+import "time"
+
+// what the game is doing between inputs
+const (
+	PhaseFalling  = "falling"  // the piece is in the air
+	PhaseLocking  = "locking"  // the piece is grounded and waiting out the lock delay
+	PhaseClearing = "clearing" // full rows are shown clearing, no piece is falling
+	PhaseSpawning = "spawning" // entry delay before the next piece appears
+)
+
+// phase returns what the game is doing now
+func (g *Game) phase() string {
+	switch {
+	case g.waitUntil == 0:
+	case g.Clearing != nil:
+		return PhaseClearing
+	default:
+		return PhaseSpawning
+	}
+	if g.grounded && g.Mode.LockDelay > 0 {
+		return PhaseLocking
+	}
+	return PhaseFalling
+}
+
+// waiting reports whether the game is in a line clear or entry delay,
+// when there is no falling piece
+func (g *Game) waiting() bool {
+	return g.waitUntil > 0
+}
+
+// delayFrom returns when a delay of ms milliseconds started at play time t
+// ends. Delays start on whole milliseconds, as replays record times, so a
+// replayed game ends them at the same events as the live one did.
+func delayFrom(t time.Duration, ms int64) time.Duration {
+	return t.Truncate(time.Millisecond) + time.Duration(ms)*time.Millisecond
+}
+
+// afterLock goes on from a locked piece at play time t: full rows are
+// shown clearing for the mode's line clear delay before they go
+func (g *Game) afterLock(t time.Duration) {
+	if rows := g.fullRows(); len(rows) > 0 && g.Mode.LineClearDelay > 0 {
+		g.Piece = nil
+		g.Clearing = rows
+		g.waitUntil = delayFrom(t, g.Mode.LineClearDelay)
+		return
+	}
+	g.clearLines()
+	g.afterClear(t)
+}
+
+// afterClear goes on once rows have cleared at play time t: the goal is
+// checked, garbage rises and the next piece comes after the entry delay
+func (g *Game) afterClear(t time.Duration) {
+	g.Clearing = nil
+	g.waitUntil = 0
+	if g.goalReached() {
+		g.complete()
+		return
+	}
+	g.riseForPiece()
+	if g.ended() {
+		return
+	}
+	if g.Mode.SpawnDelay > 0 {
+		g.Piece = nil
+		g.waitUntil = delayFrom(t, g.Mode.SpawnDelay)
+		return
+	}
+	g.spawnNext()
+}
+
+// spawnNext brings in the next piece, holding and rotating it first if
+// the player asked to during the delay before it
+func (g *Game) spawnNext() {
+	g.waitUntil = 0
+	g.spawn()
+	if g.IHS && g.Mode.Hold {
+		g.hold()
+	}
+	for i := 0; i < g.IRS && !g.ended(); i++ {
+		x, o, ok := g.rotation(g.X, g.Y, g.Orientation)
+		if !ok {
+			break
+		}
+		g.X, g.Orientation = x, o
+		g.Piece = g.def().orientation(o)
+	}
+	g.IRS, g.IHS = 0, false
+	if !g.ended() && g.collides(g.X, g.Y, g.Piece) {
+		g.topOut(BlockOut)
+	}
+}
+
+// settle ends the delays that are over by now, so whatever the game does
+// next starts from the phase it is really in
+func (g *Game) settle() {
+	for g.waiting() && !g.ended() && g.clock.elapsed() >= g.waitUntil {
+		g.endDelay()
+	}
+}
+
+// endDelay ends the current delay now, whenever it was due
+func (g *Game) endDelay() {
+	t := g.waitUntil
+	if g.Clearing != nil {
+		g.clearLines()
+		g.afterClear(t)
+		return
+	}
+	g.spawnNext()
+}
+
+// Update ends the delays that are over, reporting whether the game
+// changed. Game loops call it every frame; every other action does the
+// same first, but a delay can end the game with no action after it.
+func (g *Game) Update() bool {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if !g.waiting() || g.ended() || g.Paused {
+		return false
+	}
+	before := g.waitUntil
+	g.settle()
+	return g.waitUntil != before
+}
+
+// SkipDelays ends any line clear or entry delay at once, for players that
+// do not play in time
+func (g *Game) SkipDelays() {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.skipDelays()
+}
+
+func (g *Game) skipDelays() {
+	for g.waiting() && !g.ended() {
+		g.endDelay()
+	}
+}
+
+// phaseLeft returns how long the current delay has still to run
+func (g *Game) phaseLeft() time.Duration {
+	if !g.waiting() {
+		return 0
+	}
+	return max(g.waitUntil-g.clock.elapsed(), 0)
+}
@@ -0,0 +1,220 @@
+package model
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sync"
+)
+
+// built-in piece sets
+//
+//go:embed pieces.json
+var defaultPieceSets []byte
+
+// DefaultPieceSet is used by modes that don't name a set
+const DefaultPieceSet = "extended"
+
+// largest bounding box a piece may have
+const maxPieceSize = 8
+
+// cells keep a piece's colour and its 1-based index in the set in a byte
+// each, which limits both
+const (
+	maxPieceColor = math.MaxUint8
+	maxSetPieces  = math.MaxUint8
+)
+
+// PieceDef defines one piece of a set
+type PieceDef struct {
+	Name   string    `json:"name"`
+	Color  int       `json:"color"`            // palette index the piece is drawn with
+	Shape  []string  `json:"shape"`            // rows of a square box, '#' for a block and '.' for empty
+	Center []float64 `json:"center,omitempty"` // rotation centre x, y in cells; defaults to the middle of the box
+
+	rotations [4][]int // flattened orientations, spawn orientation first
+}
+
+// PieceSet is a named collection of pieces
+type PieceSet struct {
+	Name   string     `json:"name"`
+	Pieces []PieceDef `json:"pieces"`
+}
+
+// pieceFile is the content of a piece set file
+type pieceFile struct {
+	Sets []*PieceSet `json:"sets"`
+}
+
+var (
+	pieceSetsMu sync.RWMutex
+	pieceSets   = mustParsePieceSets(defaultPieceSets)
+)
+
+// ParsePieceSets decodes and validates piece set definitions
+func ParsePieceSets(data []byte) (map[string]*PieceSet, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var f pieceFile
+	if err := dec.Decode(&f); err != nil {
+		return nil, err
+	}
+	var errs []error
+	sets := map[string]*PieceSet{}
+	for i, set := range f.Sets {
+		if set.Name == "" {
+			errs = append(errs, fmt.Errorf("set %d: name is required", i))
+		}
+		if sets[set.Name] != nil {
+			errs = append(errs, fmt.Errorf("set %d: duplicate name %q", i, set.Name))
+		}
+		if len(set.Pieces) == 0 {
+			errs = append(errs, fmt.Errorf("set %q: no pieces defined", set.Name))
+		} else if len(set.Pieces) > maxSetPieces {
+			errs = append(errs, fmt.Errorf("set %q: %d pieces, at most %d allowed", set.Name, len(set.Pieces), maxSetPieces))
+		}
+		for j := range set.Pieces {
+			if err := set.Pieces[j].build(); err != nil {
+				errs = append(errs, fmt.Errorf("set %q: piece %d (%q): %w", set.Name, j, set.Pieces[j].Name, err))
+			}
+		}
+		sets[set.Name] = set
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return sets, nil
+}
+
+// LoadPieceSets reads and validates a piece set file
+func LoadPieceSets(path string) (map[string]*PieceSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	sets, err := ParsePieceSets(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return sets, nil
+}
+
+// AddPieceSets makes sets available to modes, replacing any of the same name
+func AddPieceSets(sets map[string]*PieceSet) {
+	pieceSetsMu.Lock()
+	defer pieceSetsMu.Unlock()
+	merged := make(map[string]*PieceSet, len(pieceSets)+len(sets))
+	for name, set := range pieceSets {
+		merged[name] = set
+	}
+	for name, set := range sets {
+		merged[name] = set
+	}
+	pieceSets = merged
+}
+
+// PieceSetByName returns a registered piece set
+func PieceSetByName(name string) (*PieceSet, bool) {
+	pieceSetsMu.RLock()
+	defer pieceSetsMu.RUnlock()
+	set, ok := pieceSets[name]
+	return set, ok
+}
+
+// mustParsePieceSets parses the built-in sets, which are known to be valid
+func mustParsePieceSets(data []byte) map[string]*PieceSet {
+	sets, err := ParsePieceSets(data)
+	if err != nil {
+		panic("built-in piece sets: " + err.Error())
+	}
+	return sets
+}
+
+// build checks the definition and precomputes its four orientations
+func (d *PieceDef) build() error {
+	n := len(d.Shape)
+	if n == 0 || n > maxPieceSize {
+		return fmt.Errorf("shape must have 1 to %d rows, got %d", maxPieceSize, n)
+	}
+	if d.Color <= 0 || d.Color > maxPieceColor {
+		return fmt.Errorf("color must be 1 to %d, got %d", maxPieceColor, d.Color)
+	}
+	base := make([]int, n*n)
+	blocks := 0
+	for y, row := range d.Shape {
+		if len(row) != n {
+			return fmt.Errorf("shape must be square: row %d has %d cells, want %d", y, len(row), n)
+		}
+		for x, c := range row {
+			switch c {
+			case '#':
+				base[y*n+x] = d.Color
+				blocks++
+			case '.':
+			default:
+				return fmt.Errorf("shape row %d has %q; use '#' or '.'", y, c)
+			}
+		}
+	}
+	if blocks == 0 {
+		return errors.New("shape has no blocks")
+	}
+	cx, cy := float64(n-1)/2, float64(n-1)/2
+	if d.Center != nil {
+		if len(d.Center) != 2 {
+			return fmt.Errorf("center must be [x, y], got %v", d.Center)
+		}
+		cx, cy = d.Center[0], d.Center[1]
+	}
+	d.rotations[0] = base
+	for r := 1; r < 4; r++ {
+		out := make([]int, n*n)
+		for i, v := range d.rotations[r-1] {
+			if v == 0 {
+				continue
+			}
+			// clockwise with y pointing down
+			nx := cx + cy - float64(i/n)
+			ny := cy - cx + float64(i%n)
+			if nx != math.Trunc(nx) || ny != math.Trunc(ny) || nx < 0 || ny < 0 || nx >= float64(n) || ny >= float64(n) {
+				return fmt.Errorf("center %v rotates blocks off the grid or outside the box", []float64{cx, cy})
+			}
+			out[int(ny)*n+int(nx)] = v
+		}
+		d.rotations[r] = out
+	}
+	return nil
+}
+
+// size returns the side of the piece's box
+func (d *PieceDef) size() int {
+	return len(d.Shape)
+}
+
+// orientation returns a copy of the piece turned r times clockwise
+func (d *PieceDef) orientation(r int) []int {
+	p := make([]int, len(d.rotations[r%4]))
+	copy(p, d.rotations[r%4])
+	return p
+}
+
+// maxSize returns the side of the largest box in the set
+func (set *PieceSet) maxSize() int {
+	n := 0
+	for i := range set.Pieces {
+		n = max(n, set.Pieces[i].size())
+	}
+	return n
+}
+
+// pieceSet returns the name of the mode's piece set
+func (m GameMode) pieceSet() string {
+	if m.PieceSet != "" {
+		return m.PieceSet
+	}
+	return DefaultPieceSet
+}
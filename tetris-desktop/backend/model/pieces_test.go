@@ -0,0 +1,51 @@
+package model
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// pieceSetFile returns a piece set file with one set of n one-block pieces
+// in the given colour
+func pieceSetFile(t *testing.T, n, color int) []byte {
+	t.Helper()
+	set := &PieceSet{Name: "test"}
+	for range n {
+		set.Pieces = append(set.Pieces, PieceDef{Name: "dot", Color: color, Shape: []string{"#"}})
+	}
+	data, err := json.Marshal(pieceFile{Sets: []*PieceSet{set}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestParsePieceSetsLimits(t *testing.T) {
+	tests := []struct {
+		name   string
+		pieces int
+		color  int
+		want   string // in the error; empty for none
+	}{
+		{"largest colour", 1, 255, ""},
+		{"colour zero", 1, 0, "color must be 1 to 255, got 0"},
+		{"colour past a byte", 1, 256, "color must be 1 to 255, got 256"},
+		{"largest set", 255, 1, ""},
+		{"set past a byte", 256, 1, "256 pieces, at most 255 allowed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sets, err := ParsePieceSets(pieceSetFile(t, tt.pieces, tt.color))
+			if tt.want == "" {
+				if err != nil || len(sets["test"].Pieces) != tt.pieces {
+					t.Fatalf("got %v, want the set loaded", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("got error %v, want one saying %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package model
+
+import "slices"
+
+// Inputs making up a placement's path, named like the websocket messages
+const (
+	InputLeft   = "left"
+	InputRight  = "right"
+	InputDown   = "down"
+	InputRotate = "rotate"
+	InputDrop   = "drop"
+)
+
+// Point is a board cell, counting rows from the top of the hidden buffer
+type Point struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+// Placement is a final resting spot for the falling piece together with the
+// shortest sequence of inputs that gets it there from where it is now
+type Placement struct {
+	X           int      `json:"x"`
+	Y           int      `json:"y"`
+	Orientation int      `json:"orientation"`
+	Cells       []Point  `json:"cells"`
+	Path        []string `json:"path"`
+}
+
+// pose is a position of the falling piece during the search
+type pose struct {
+	x, y, o int
+}
+
+// Placements lists every legal final placement for the falling piece,
+// including tucks and spins that need soft drops before sliding or rotating.
+// Placements that fill the same cells are only listed once.
+func (g *Game) Placements() []Placement {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return g.placements()
+}
+
+// Place moves the falling piece to p and locks it as one step. The placement
+// must fill the cells of one Placements would return. Line clear and entry
+// delays are skipped, before and after, as whole placements are not played
+// in time. When p has a path it is checked by replaying it, otherwise by
+// searching for a placement filling the same cells.
+func (g *Game) Place(p Placement) bool {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if g.ended() || g.Paused {
+		return false
+	}
+	g.skipDelays()
+	if g.ended() {
+		return false
+	}
+	if p.Orientation < 0 || p.Orientation > 3 {
+		return false
+	}
+	target := pose{p.X, p.Y, p.Orientation}
+	legal := false
+	if len(p.Path) > 0 {
+		end, ok := g.replay(p.Path)
+		legal = ok && end == target
+	} else {
+		// placements are listed once per set of cells, so other poses
+		// filling the same cells are just as legal
+		want := cellsKey(g.cells(target, g.def().rotations[target.o]))
+		for _, q := range g.placements() {
+			if cellsKey(q.Cells) == want {
+				legal = true
+				break
+			}
+		}
+	}
+	if !legal {
+		return false
+	}
+	g.X, g.Y = p.X, p.Y
+	g.Orientation = p.Orientation
+	g.Piece = g.def().orientation(p.Orientation)
+	g.placing = true
+	g.lock()
+	g.placing = false
+	g.skipDelays()
+	return true
+}
+
+// replay follows inputs from the falling piece's pose without changing the
+// game, returning where a final drop leaves the piece
+func (g *Game) replay(inputs []string) (pose, bool) {
+	cur := pose{g.X, g.Y, g.Orientation}
+	for i, input := range inputs {
+		piece := g.def().rotations[cur.o]
+		next := cur
+		switch input {
+		case InputLeft:
+			next.x--
+		case InputRight:
+			next.x++
+		case InputDown:
+			next.y++
+		case InputRotate:
+			x, o, ok := g.rotation(cur.x, cur.y, cur.o)
+			if !ok {
+				return cur, false
+			}
+			next = pose{x, cur.y, o}
+		case InputDrop:
+			if i != len(inputs)-1 {
+				return cur, false
+			}
+			for !g.collides(next.x, next.y+1, piece) {
+				next.y++
+			}
+			return next, true
+		default:
+			return cur, false
+		}
+		if g.collides(next.x, next.y, g.def().rotations[next.o]) {
+			return cur, false
+		}
+		cur = next
+	}
+	return cur, false
+}
+
+// step is a pose reached during the search and the input that reached it
+type step struct {
+	pose
+	parent int
+	input  string
+}
+
+// placements searches breadth first over moves and rotations, so the first
+// path found to each landing spot is a shortest one. Gravity is ignored.
+func (g *Game) placements() []Placement {
+	if g.ended() || g.Piece == nil {
+		return nil
+	}
+	start := pose{g.X, g.Y, g.Orientation}
+	steps := []step{{pose: start, parent: -1}}
+	visited := map[pose]bool{start: true}
+	landed := map[pose]bool{}
+	seen := map[string]bool{}
+	var result []Placement
+
+	// Above the stack a soft drop changes nothing a hard drop would not, so
+	// the search only steps down once the piece's box reaches the surface
+	surface := g.rows()
+	for y, row := range g.Board {
+		if slices.ContainsFunc(row, Cell.Filled) {
+			surface = y
+			break
+		}
+	}
+
+	for i := 0; i < len(steps); i++ {
+		cur := steps[i].pose
+		piece := g.def().rotations[cur.o]
+
+		// every pose can hard drop to a placement
+		land := cur
+		for !g.collides(land.x, land.y+1, piece) {
+			land.y++
+		}
+		if !landed[land] {
+			landed[land] = true
+			cells := g.cells(land, piece)
+			if key := cellsKey(cells); !seen[key] {
+				seen[key] = true
+				result = append(result, Placement{
+					X:           land.x,
+					Y:           land.y,
+					Orientation: land.o,
+					Cells:       cells,
+					Path:        append(pathTo(steps, i), InputDrop),
+				})
+			}
+		}
+
+		next := func(p pose, input string) {
+			if visited[p] {
+				return
+			}
+			visited[p] = true
+			steps = append(steps, step{p, i, input})
+		}
+		if !g.collides(cur.x-1, cur.y, piece) {
+			next(pose{cur.x - 1, cur.y, cur.o}, InputLeft)
+		}
+		if !g.collides(cur.x+1, cur.y, piece) {
+			next(pose{cur.x + 1, cur.y, cur.o}, InputRight)
+		}
+		if cur.y+pieceSize(piece) >= surface && !g.collides(cur.x, cur.y+1, piece) {
+			next(pose{cur.x, cur.y + 1, cur.o}, InputDown)
+		}
+		if x, o, ok := g.rotation(cur.x, cur.y, cur.o); ok {
+			next(pose{x, cur.y, o}, InputRotate)
+		}
+	}
+	return result
+}
+
+// pathTo follows parents back from steps[i] to the start
+func pathTo(steps []step, i int) []string {
+	var path []string
+	for ; steps[i].parent >= 0; i = steps[i].parent {
+		path = append(path, steps[i].input)
+	}
+	slices.Reverse(path)
+	return path
+}
+
+// cells returns the board cells piece fills at p, in reading order
+func (g *Game) cells(p pose, piece []int) []Point {
+	n := pieceSize(piece)
+	var cells []Point
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			if piece[y*n+x] != 0 {
+				cells = append(cells, Point{p.x + x, p.y + y})
+			}
+		}
+	}
+	return cells
+}
+
+func cellsKey(cells []Point) string {
+	b := make([]byte, 0, 2*len(cells))
+	for _, c := range cells {
+		b = append(b, byte(c.X), byte(c.Y))
+	}
+	return string(b)
+}
@@ -0,0 +1,107 @@
+package model
+
+import "testing"
+
+// testMode is a plain mode on the standard pieces with nothing to wait for
+var testMode = GameMode{ID: "test", FallSpeed: 1, PieceSet: "standard", ScoreMultiplier: 1}
+
+// newTestGame returns a game on an empty board whose falling piece is the
+// named one, just entered
+func newTestGame(t *testing.T, mode GameMode, piece string) *Game {
+	t.Helper()
+	g := NewSeededGame(mode, 1)
+	for i, def := range g.pieces.Pieces {
+		if def.Name == piece {
+			g.setPiece(i)
+			g.placeAtSpawn()
+			return g
+		}
+	}
+	t.Fatalf("no piece %q in set %q", piece, g.pieces.Name)
+	return nil
+}
+
+func TestPlacementsCount(t *testing.T) {
+	tests := []struct {
+		piece string
+		want  int
+	}{
+		{"O", 9},  // one shape, nine columns
+		{"I", 17}, // 7 flat and 10 upright
+		{"S", 17}, // 8 flat and 9 upright
+		{"T", 34}, // 8 flat each way and 9 upright each way
+		{"L", 34}, // as T
+	}
+	for _, tt := range tests {
+		t.Run(tt.piece, func(t *testing.T) {
+			g := newTestGame(t, testMode, tt.piece)
+			ps := g.Placements()
+			if len(ps) != tt.want {
+				t.Fatalf("got %d placements, want %d", len(ps), tt.want)
+			}
+			seen := map[string]bool{}
+			for _, p := range ps {
+				key := cellsKey(p.Cells)
+				if seen[key] {
+					t.Errorf("cells %q listed twice", key)
+				}
+				seen[key] = true
+			}
+		})
+	}
+}
+
+func TestPlace(t *testing.T) {
+	tests := []struct {
+		name  string
+		piece string
+		edit  func(p *Placement)
+		want  bool
+	}{
+		{"listed", "T", func(p *Placement) {}, true},
+		{"listed without path", "T", func(p *Placement) { p.Path = nil }, true},
+		{"other pose same cells", "O", func(p *Placement) { p.Orientation = 2; p.Path = nil }, true},
+		{"orientation too big", "T", func(p *Placement) { p.Orientation = 4; p.Path = nil }, false},
+		{"negative orientation", "T", func(p *Placement) { p.Orientation = -1; p.Path = nil }, false},
+		{"in the air", "T", func(p *Placement) { p.Y -= 5; p.Path = nil }, false},
+		{"path ending elsewhere", "T", func(p *Placement) { p.Path = []string{InputRight, InputDrop} }, false},
+		{"drop before the end", "T", func(p *Placement) { p.Path = []string{InputDrop, InputLeft} }, false},
+		{"unknown input", "T", func(p *Placement) { p.Path = []string{"jump"} }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newTestGame(t, testMode, tt.piece)
+			p := g.Placements()[0]
+			want := cellsKey(p.Cells)
+			tt.edit(&p)
+			if got := g.Place(p); got != tt.want {
+				t.Fatalf("Place = %v, want %v", got, tt.want)
+			}
+			placed := 0
+			for _, row := range g.Board {
+				for _, c := range row {
+					if c.Filled() {
+						placed++
+					}
+				}
+			}
+			if !tt.want {
+				if placed != 0 || g.Pieces != 0 {
+					t.Fatalf("refused placement changed the game: %d cells, %d pieces", placed, g.Pieces)
+				}
+				return
+			}
+			var cells []Point
+			for y, row := range g.Board {
+				for x, c := range row {
+					if c.Filled() {
+						cells = append(cells, Point{x, y})
+					}
+				}
+			}
+			if got := cellsKey(cells); got != want {
+				t.Fatalf("filled %q, want %q", got, want)
+			}
+		})
+	}
+}
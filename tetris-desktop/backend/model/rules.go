@@ -0,0 +1,151 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+)
+
+// randomizers choosing the next piece
+const (
+	RandomizerRandom = "random" // every piece independently random
+	RandomizerBag    = "bag"    // each piece once per shuffled bag
+)
+
+// rotation systems
+const (
+	RotationSimple  = "simple"  // rotate in place or kick up to two cells sideways
+	RotationClassic = "classic" // rotate in place only
+)
+
+// queue length when a mode doesn't set PreviewCount
+const defaultPreview = 3
+
+// longest next queue a mode may ask for
+const maxPreview = 6
+
+// Validate reports every problem with the mode's rules
+func (m GameMode) Validate() error {
+	var errs []error
+	bad := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+	if m.ID == "" {
+		bad("id is required")
+	}
+	if m.Name == "" {
+		bad("name is required")
+	}
+	if m.FallSpeed < 1 && len(m.Gravity) == 0 {
+		bad("fallSpeed must be at least 1, got %d", m.FallSpeed)
+	}
+	for i, ms := range m.Gravity {
+		if ms <= 0 {
+			bad("gravity[%d] must be positive, got %d", i, ms)
+		}
+	}
+	if m.LockDelay < 0 {
+		bad("lockDelay must not be negative, got %d", m.LockDelay)
+	}
+	if m.LineClearDelay < 0 || m.SpawnDelay < 0 {
+		bad("lineClearDelay and spawnDelay must not be negative")
+	}
+	if m.PreviewCount < 0 || m.PreviewCount > maxPreview {
+		bad("previewCount must be between 0 and %d, got %d", maxPreview, m.PreviewCount)
+	}
+	switch m.Randomizer {
+	case "", RandomizerRandom, RandomizerBag:
+	default:
+		bad("randomizer %q must be %q or %q", m.Randomizer, RandomizerRandom, RandomizerBag)
+	}
+	switch m.Rotation {
+	case "", RotationSimple, RotationClassic:
+	default:
+		bad("rotation %q must be %q or %q", m.Rotation, RotationSimple, RotationClassic)
+	}
+	if m.ScoreMultiplier <= 0 {
+		bad("scoreMultiplier must be positive, got %g", m.ScoreMultiplier)
+	}
+	for i, pts := range m.LineScores {
+		if pts < 0 {
+			bad("lineScores[%d] must not be negative, got %d", i, pts)
+		}
+	}
+	switch m.Ranking {
+	case RankByScore:
+	case RankByTime:
+		if m.Goal.Lines == 0 && !m.Goal.Garbage {
+			bad("ranking %q needs a lines or garbage goal to finish on", RankByTime)
+		}
+	default:
+		bad("ranking %q must be %q or %q", m.Ranking, RankByScore, RankByTime)
+	}
+	if m.Goal.Lines < 0 || m.Goal.TimeLimit < 0 {
+		bad("goal lines and timeLimit must not be negative")
+	}
+	if m.Goal.Garbage && m.Garbage.total() == 0 {
+		bad("garbage goal needs garbage rows")
+	}
+	if m.Garbage.Messiness < 0 || m.Garbage.Messiness > 1 {
+		bad("garbage messiness must be between 0 and 1, got %g", m.Garbage.Messiness)
+	}
+	if m.Garbage.Rows < 0 || m.Garbage.Total < 0 || m.Garbage.Holes < 0 {
+		bad("garbage rows, total and holes must not be negative")
+	}
+	if m.Width != 0 && (m.Width < minWidth || m.Width > maxWidth) {
+		bad("width must be between %d and %d, got %d", minWidth, maxWidth, m.Width)
+	}
+	if m.Height != 0 && (m.Height < minHeight || m.Height > maxHeight) {
+		bad("height must be between %d and %d, got %d", minHeight, maxHeight, m.Height)
+	}
+	if m.Buffer != 0 && (m.Buffer < minBuffer || m.Buffer > maxBuffer) {
+		bad("buffer must be between %d and %d, got %d", minBuffer, maxBuffer, m.Buffer)
+	}
+	if set, ok := PieceSetByName(m.pieceSet()); !ok {
+		bad("pieceSet %q is not defined", m.pieceSet())
+	} else if n := set.maxSize(); m.buffer() < n || m.width() < n {
+		bad("width and buffer must fit the %d-cell pieces of set %q", n, set.Name)
+	}
+	if m.LevelLines < 0 {
+		bad("levelLines must not be negative, got %d", m.LevelLines)
+	}
+	return errors.Join(errs...)
+}
+
+// previewCount returns the length of the next queue
+func (m GameMode) previewCount() int {
+	if m.PreviewCount > 0 {
+		return m.PreviewCount
+	}
+	return defaultPreview
+}
+
+// ShownPreview returns how many pieces of the next queue players see: none
+// when the mode has no next preview
+func (m GameMode) ShownPreview() int {
+	if !m.NextPreview {
+		return 0
+	}
+	return m.previewCount()
+}
+
+// nextPieceID picks the next piece using the mode's randomizer
+func (g *Game) nextPieceID() int {
+	if g.Mode.Randomizer != RandomizerBag {
+		return g.rng.Intn(len(g.pieces.Pieces))
+	}
+	if len(g.bag) == 0 {
+		g.bag = g.rng.Perm(len(g.pieces.Pieces))
+	}
+	id := g.bag[0]
+	g.bag = g.bag[1:]
+	return id
+}
+
+// lineScore returns the points for clearing n lines at once
+func (m GameMode) lineScore(n int) int {
+	base := n * 100 * n
+	if len(m.LineScores) > 0 {
+		base = m.LineScores[min(n, len(m.LineScores))-1]
+	}
+	return int(float64(base) * m.ScoreMultiplier)
+}
@@ -1,12 +1,14 @@
 package model
 
+import "slices"
+
 // Snapshot returns a deep copy of the game state safe for sending
 func (g *Game) Snapshot() GameState {
 	g.mutex.Lock()
 	defer g.mutex.Unlock()
-	b := make([][]int, len(g.Board))
+	b := make([][]Cell, len(g.Board))
 	for i := range g.Board {
-		row := make([]int, len(g.Board[i]))
+		row := make([]Cell, len(g.Board[i]))
 		copy(row, g.Board[i])
 		b[i] = row
 	}
@@ -22,16 +24,53 @@ func (g *Game) Snapshot() GameState {
 		copy(pi, g.Next[i])
 		n[i] = pi
 	}
+	var h []int
+	if g.Held != nil {
+		h = make([]int, len(g.Held))
+		copy(h, g.Held)
+	}
+	var finesse *FinesseCheck
+	if g.Finesse != nil {
+		f := *g.Finesse
+		f.Path = slices.Clone(f.Path)
+		finesse = &f
+	}
+	var summary *Stats
+	if g.ended() {
+		stats := g.statsNow()
+		summary = &stats
+	}
 	return GameState{
-		Board:    b,
-		Piece:    p,
-		Next:     n,
-		PieceID:  g.PieceID,
-		X:        g.X,
-		Y:        g.Y,
-		Score:    g.Score,
-		GameOver: g.GameOver,
-		Paused:   g.Paused,
-		Mode:     g.Mode,
+		Board:       b,
+		Width:       g.Width,
+		Height:      g.Height,
+		Hidden:      g.Hidden,
+		Piece:       p,
+		Next:        n,
+		PieceID:     g.PieceID,
+		Orientation: g.Orientation,
+		X:           g.X,
+		Y:           g.Y,
+		Score:       g.Score,
+		Lines:       g.Lines,
+		Level:       g.Level,
+		Pieces:      g.Pieces,
+		GarbageLeft: g.GarbageLeft,
+		Elapsed:     g.clock.elapsed().Milliseconds(),
+		GameOver:    g.GameOver,
+		TopOut:      g.TopOut,
+		Completed:   g.Completed,
+		Paused:      g.Paused,
+		Held:        h,
+		HoldUsed:    g.HoldUsed,
+		Phase:       g.phase(),
+		PhaseLeft:   g.phaseLeft().Milliseconds(),
+		Clearing:    slices.Clone(g.Clearing),
+		IRS:         g.IRS,
+		IHS:         g.IHS,
+		Summary:     summary,
+		Finesse:     finesse,
+		Seed:        g.Seed,
+		Mode:        g.Mode,
 	}
 }
@@ -1,58 +1,82 @@
 package model
 
 import (
-	"log"
+	"log/slog"
 	"math/rand"
 )
 
-// NewGame creates a new game instance
+// NewGame creates a new game instance with a random seed
 func NewGame(mode GameMode) *Game {
-	b := make([][]int, Rows)
-	for i := range b {
-		b[i] = make([]int, Cols)
+	return NewSeededGame(mode, rand.Int63())
+}
+
+// NewSeededGame creates a new game instance whose pieces and garbage are
+// drawn from seed, so the same seed and inputs always play out the same
+func NewSeededGame(mode GameMode, seed int64) *Game {
+	set, ok := PieceSetByName(mode.pieceSet())
+	if !ok {
+		slog.Warn("Unknown piece set", "set", mode.pieceSet(), "using", DefaultPieceSet)
+		set, _ = PieceSetByName(DefaultPieceSet)
+	}
+	g := &Game{
+		Board:  newBoard(mode.buffer()+mode.height(), mode.width()),
+		Width:  mode.width(),
+		Height: mode.height(),
+		Hidden: mode.buffer(),
+		Mode:   mode,
+		Level:  1,
+		Seed:   seed,
+		pieces: set,
+		rng:    rand.New(rand.NewSource(seed)),
+		combo:  -1,
 	}
-	g := &Game{Board: b, Mode: mode}
+	g.fillGarbage()
 	// initialize next queue
-	g.Next = make([][]int, 0, 3)
-	for i := 0; i < 3; i++ {
-		id := rand.Intn(len(Tetrominoes))
-		g.Next = append(g.Next, Flatten(Tetrominoes[id]))
+	g.queue = make([]int, 0, mode.previewCount())
+	for i := 0; i < mode.previewCount(); i++ {
+		g.queue = append(g.queue, g.nextPieceID())
 	}
 	g.spawn()
-	log.Println("New game created. X:", g.X, "Y:", g.Y, "GameOver:", g.GameOver)
+	g.clock.start()
+	slog.Debug("New game created", "mode", mode.ID, "seed", seed, "x", g.X, "y", g.Y, "gameOver", g.GameOver)
 	return g
 }
 
 // spawn places a new piece onto the board
 func (g *Game) spawn() {
-	if len(g.Next) == 0 {
-		id := rand.Intn(len(Tetrominoes))
-		g.Piece = Flatten(Tetrominoes[id])
-		g.PieceID = id + 1
-	} else {
-		// take first from next queue
-		g.Piece = make([]int, 16)
-		copy(g.Piece, g.Next[0])
-		pid := 0
-		for _, v := range g.Piece {
-			if v != 0 {
-				pid = v
-				break
-			}
-		}
-		// set piece ID
-		g.PieceID = pid
-		if len(g.Next) > 1 {
-			g.Next = append(g.Next[:0], g.Next[1:]...)
-		} else {
-			g.Next = g.Next[:0]
-		}
-		// add new piece to queue
-		id := rand.Intn(len(Tetrominoes))
-		g.Next = append(g.Next, Flatten(Tetrominoes[id]))
+	// take first from next queue and add a new piece to its end
+	g.setPiece(g.queue[0])
+	g.queue = append(g.queue[1:], g.nextPieceID())
+	g.Next = make([][]int, len(g.queue))
+	for i, id := range g.queue {
+		g.Next[i] = g.pieces.Pieces[id].orientation(0)
+	}
+	g.placeAtSpawn()
+}
+
+// setPiece makes the piece at set index id the falling piece, in its spawn orientation
+func (g *Game) setPiece(id int) {
+	g.Piece = g.pieces.Pieces[id].orientation(0)
+	g.PieceID = id + 1
+	g.Orientation = 0
+}
+
+// def returns the definition of the falling piece
+func (g *Game) def() *PieceDef {
+	return &g.pieces.Pieces[g.PieceID-1]
+}
+
+// Upcoming returns the 1-based ids of the pieces in the next queue, and of
+// the held piece or 0 when the hold slot is empty
+func (g *Game) Upcoming() (next []int, held int) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	next = make([]int, len(g.queue))
+	for i, id := range g.queue {
+		next[i] = id + 1
+	}
+	if g.Held != nil {
+		held = g.held + 1
 	}
-	// center piece
-	g.X = (Cols / 2) - 2
-	// start above the board
-	g.Y = -1
+	return next, held
 }
@@ -1,37 +1,130 @@
 package model
 
-import "sync"
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// default game board dimensions
+const (
+	Rows   = 20 // visible rows
+	Cols   = 10
+	Buffer = 20 // hidden rows above the visible field
+)
+
+// why a game topped out
+const (
+	BlockOut = "block out" // a new piece spawned overlapping the stack
+	LockOut  = "lock out"  // a piece locked entirely above the visible field
+	PushOut  = "push out"  // garbage pushed blocks off the top of the board
+)
 
-// game board dimensions
+// leaderboard ordering used by a mode
 const (
-	Rows = 20
-	Cols = 10
+	RankByScore = "score"
+	RankByTime  = "time"
 )
 
+// Goal describes when a mode ends other than by topping out.
+// A zero Goal means the game is endless.
+type Goal struct {
+	Lines     int   `json:"lines,omitempty"`
+	TimeLimit int64 `json:"timeLimit,omitempty"` // milliseconds
+	Garbage   bool  `json:"garbage,omitempty"`   // clear every garbage row
+}
+
+// Choices lists the goal variants a client may pick for a mode
+type Choices struct {
+	Lines   []int `json:"lines,omitempty"`   // line goal, or garbage rows in dig modes
+	Minutes []int `json:"minutes,omitempty"` // time limit
+}
+
 // GameMode is the difficulty/options structure
 type GameMode struct {
+	ID              string  `json:"id"`
 	Name            string  `json:"name"`
 	GhostPiece      bool    `json:"ghostPiece"`
 	NextPreview     bool    `json:"nextPreview"`
+	PreviewCount    int     `json:"previewCount,omitempty"` // pieces in the next queue; defaults to 3
 	CanPause        bool    `json:"canPause"`
+	Hold            bool    `json:"hold"`
 	FallSpeed       int     `json:"fallSpeed"`
+	Gravity         []int64 `json:"gravity,omitempty"`        // milliseconds per row by level; overrides FallSpeed
+	LockDelay       int64   `json:"lockDelay,omitempty"`      // milliseconds a grounded piece waits before locking
+	LineClearDelay  int64   `json:"lineClearDelay,omitempty"` // milliseconds full rows are shown clearing
+	SpawnDelay      int64   `json:"spawnDelay,omitempty"`     // milliseconds before the next piece enters (ARE)
+	PieceSet        string  `json:"pieceSet,omitempty"`       // defaults to DefaultPieceSet
+	Randomizer      string  `json:"randomizer,omitempty"`
+	Rotation        string  `json:"rotation,omitempty"`
 	ScoreMultiplier float64 `json:"scoreMultiplier"`
+	LineScores      []int   `json:"lineScores,omitempty"` // points for 1, 2, 3... lines at once
+	Goal            Goal    `json:"goal"`
+	Ranking         string  `json:"ranking"`
+	LevelLines      int     `json:"levelLines,omitempty"`      // lines per level; 0 stays at level 1
+	Width           int     `json:"width,omitempty"`           // columns; defaults to Cols
+	Height          int     `json:"height,omitempty"`          // visible rows; defaults to Rows
+	Buffer          int     `json:"buffer,omitempty"`          // hidden rows above the field; defaults to Buffer
+	FinesseTraining bool    `json:"finesseTraining,omitempty"` // a piece placed with a finesse fault goes back to the top
+	Garbage         Garbage `json:"garbage"`
+	Choices         Choices `json:"choices"`
 }
 
 // Game is the core game state
 type Game struct {
-	Board     [][]int  `json:"board"`
-	Piece     []int    `json:"piece"`
-	Next      [][]int  `json:"next"`
-	PieceID   int      `json:"pieceId"`
-	X         int      `json:"x"`
-	Y         int      `json:"y"`
-	Score     int      `json:"score"`
-	GameOver  bool     `json:"gameOver"`
-	Paused    bool     `json:"paused"`
-	HighScore int      `json:"Highscore"`
-	Mode      GameMode `json:"mode"`
-	mutex     sync.Mutex
+	Board       [][]Cell      `json:"board"` // Hidden buffer rows, then Height visible rows
+	Width       int           `json:"width"`
+	Height      int           `json:"height"` // visible rows
+	Hidden      int           `json:"hidden"` // buffer rows at the top of Board
+	Piece       []int         `json:"piece"`
+	Next        [][]int       `json:"next"`
+	PieceID     int           `json:"pieceId"`     // 1-based index of the piece in its set
+	Orientation int           `json:"orientation"` // clockwise turns from the spawn orientation
+	X           int           `json:"x"`
+	Y           int           `json:"y"`
+	Score       int           `json:"score"`
+	Lines       int           `json:"lines"`
+	Level       int           `json:"level"`
+	Pieces      int           `json:"pieces"`           // pieces placed
+	GarbageLeft int           `json:"garbageLeft"`      // garbage rows still to clear
+	Elapsed     int64         `json:"elapsed"`          // play time in milliseconds
+	GameOver    bool          `json:"gameOver"`         // topped out
+	TopOut      string        `json:"topOut,omitempty"` // BlockOut, LockOut or PushOut
+	Completed   bool          `json:"completed"`        // finished the mode's goal
+	Paused      bool          `json:"paused"`
+	Held        []int         `json:"hold"`                // piece in the hold slot
+	HoldUsed    bool          `json:"holdUsed"`            // hold already used for this piece
+	Phase       string        `json:"phase"`               // PhaseFalling, PhaseLocking, PhaseClearing or PhaseSpawning
+	PhaseLeft   int64         `json:"phaseLeft,omitempty"` // milliseconds left of a line clear or entry delay
+	Clearing    []int         `json:"clearing,omitempty"`  // rows of Board being cleared
+	IRS         int           `json:"irs,omitempty"`       // clockwise turns the next piece spawns with
+	IHS         bool          `json:"ihs,omitempty"`       // the next piece goes straight to hold
+	Summary     *Stats        `json:"stats,omitempty"`     // statistics, once the game has ended
+	Finesse     *FinesseCheck `json:"finesse,omitempty"`   // finesse of the last piece placed
+	HighScore   int           `json:"Highscore"`
+	Seed        int64         `json:"seed"` // seed the pieces and garbage are drawn with
+	Mode        GameMode      `json:"mode"`
+	mutex       sync.Mutex
+	clock       clock
+	rng         *rand.Rand
+
+	garbageSent int           // garbage rows generated so far
+	holes       []int         // hole columns of the last garbage row
+	nextRise    time.Duration // play time at which garbage next rises
+	pieces      *PieceSet     // set the pieces are drawn from
+	queue       []int         // set indexes of the upcoming pieces
+	held        int           // set index of the held piece, when Held is set
+	bag         []int         // set indexes left in the current bag
+	groundedAt  time.Duration // play time at which the piece touched down
+	grounded    bool
+	waitUntil   time.Duration // play time a line clear or entry delay ends; 0 when none
+
+	stats       Stats
+	combo       int  // clearing pieces in a row after the first; -1 after a piece clears nothing
+	pieceInputs int  // inputs moving or turning the falling piece, for finesse
+	spawnPose   pose // where the falling piece entered
+	rotated     bool // the falling piece's last move was a rotation
+	placing     bool // a whole placement is locking, which has no inputs to judge
 }
 
 // GameState is a copy safe to send over the wire
@@ -0,0 +1,164 @@
+package model
+
+import "maps"
+
+// Stats sum up how a game was played, for the summary at its end
+type Stats struct {
+	PiecesByType   map[string]int `json:"piecesByType"` // pieces placed by name
+	Singles        int            `json:"singles"`
+	Doubles        int            `json:"doubles"`
+	Triples        int            `json:"triples"`
+	Tetrises       int            `json:"tetrises"`      // four or more lines at once
+	TSpins         int            `json:"tSpins"`        // T pieces rotated into a spot with three corners filled
+	MaxCombo       int            `json:"maxCombo"`      // most clearing pieces in a row after the first
+	Holes          int            `json:"holes"`         // covered empty cells made by placing pieces
+	FinesseFaults  int            `json:"finesseFaults"` // pieces placed with more presses than needed
+	FinesseByType  map[string]int `json:"finesseByType"` // finesse faults by piece name
+	Inputs         int            `json:"inputs"`        // key presses that did something; held key repeats are not counted
+	PPS            float64        `json:"pps"`           // pieces per second of play time
+	InputsPerPiece float64        `json:"inputsPerPiece"`
+}
+
+// Add sums o into s, keeping the larger combo. Rates are left to the caller.
+func (s *Stats) Add(o Stats) {
+	if s.PiecesByType == nil {
+		s.PiecesByType = map[string]int{}
+	}
+	for name, n := range o.PiecesByType {
+		s.PiecesByType[name] += n
+	}
+	s.Singles += o.Singles
+	s.Doubles += o.Doubles
+	s.Triples += o.Triples
+	s.Tetrises += o.Tetrises
+	s.TSpins += o.TSpins
+	s.MaxCombo = max(s.MaxCombo, o.MaxCombo)
+	s.Holes += o.Holes
+	s.FinesseFaults += o.FinesseFaults
+	if len(o.FinesseByType) > 0 && s.FinesseByType == nil {
+		s.FinesseByType = map[string]int{}
+	}
+	for name, n := range o.FinesseByType {
+		s.FinesseByType[name] += n
+	}
+	s.Inputs += o.Inputs
+}
+
+// Stats returns the game's statistics so far
+func (g *Game) Stats() Stats {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return g.statsNow()
+}
+
+func (g *Game) statsNow() Stats {
+	s := g.stats
+	s.PiecesByType = maps.Clone(g.stats.PiecesByType)
+	if s.PiecesByType == nil {
+		s.PiecesByType = map[string]int{}
+	}
+	s.FinesseByType = maps.Clone(g.stats.FinesseByType)
+	if s.FinesseByType == nil {
+		s.FinesseByType = map[string]int{}
+	}
+	if secs := g.clock.elapsed().Seconds(); secs > 0 {
+		s.PPS = float64(g.Pieces) / secs
+	}
+	if g.Pieces > 0 {
+		s.InputsPerPiece = float64(s.Inputs) / float64(g.Pieces)
+	}
+	return s
+}
+
+// countPiece notes the falling piece as it locks, before its blocks go
+// on the board
+func (g *Game) countPiece() {
+	def := g.def()
+	if g.stats.PiecesByType == nil {
+		g.stats.PiecesByType = map[string]int{}
+	}
+	g.stats.PiecesByType[def.Name]++
+	if g.tSpin() {
+		g.stats.TSpins++
+	}
+	g.pieceInputs = 0
+}
+
+// countClear notes the lines a locked piece cleared, keeping the combo
+func (g *Game) countClear(lines int) {
+	switch {
+	case lines == 0:
+		g.combo = -1
+		return
+	case lines == 1:
+		g.stats.Singles++
+	case lines == 2:
+		g.stats.Doubles++
+	case lines == 3:
+		g.stats.Triples++
+	default:
+		g.stats.Tetrises++
+	}
+	g.combo++
+	g.stats.MaxCombo = max(g.stats.MaxCombo, g.combo)
+}
+
+// coveredCells counts the empty cells with a filled cell somewhere above them
+func (g *Game) coveredCells() int {
+	n := 0
+	for x := 0; x < g.Width; x++ {
+		covered := false
+		for y := 0; y < g.rows(); y++ {
+			if g.Board[y][x].Filled() {
+				covered = true
+			} else if covered {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// tSpin reports whether the falling piece is a T that got where it is by
+// rotating, with at least three of the four cells diagonal to its middle
+// block filled or outside the board
+func (g *Game) tSpin() bool {
+	def := g.def()
+	if def.Name != "T" || !g.rotated {
+		return false
+	}
+	cells := g.cells(pose{g.X, g.Y, g.Orientation}, g.Piece)
+	if len(cells) != 4 {
+		return false
+	}
+	filled := func(x, y int) bool {
+		return x < 0 || x >= g.Width || y < 0 || y >= g.rows() || g.Board[y][x].Filled()
+	}
+	for _, mid := range cells {
+		// the middle block touches the other three
+		touching := 0
+		for _, c := range cells {
+			if abs(c.X-mid.X)+abs(c.Y-mid.Y) == 1 {
+				touching++
+			}
+		}
+		if touching != 3 {
+			continue
+		}
+		corners := 0
+		for _, d := range [][2]int{{-1, -1}, {1, -1}, {-1, 1}, {1, 1}} {
+			if filled(mid.X+d[0], mid.Y+d[1]) {
+				corners++
+			}
+		}
+		return corners >= 3
+	}
+	return false
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
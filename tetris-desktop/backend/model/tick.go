@@ -1,17 +1,61 @@
 package model
 
+import "time"
+
+// fastest gravity any level can reach
+const minFallInterval = 50 * time.Millisecond
+
 // Step advances the game by one tick
 func (g *Game) Step() {
 	g.mutex.Lock()
 	defer g.mutex.Unlock()
 
-	if g.GameOver || g.Paused {
+	if g.ended() || g.Paused {
+		return
+	}
+
+	if g.timeUp() {
+		g.complete()
 		return
 	}
 
-	if !g.collides(g.X, g.Y+1, g.Piece) {
-		g.Y++
-	} else {
+	g.settle()
+	g.riseForTime()
+	if g.ended() || g.waiting() {
+		return
+	}
+
+	if !g.shift(0, 1) && g.lockDelayOver() {
 		g.lock()
 	}
 }
+
+// lockDelayOver reports whether a grounded piece has waited out the mode's
+// lock delay, starting the wait the first time it is asked
+func (g *Game) lockDelayOver() bool {
+	if g.Mode.LockDelay <= 0 {
+		return true
+	}
+	if !g.grounded {
+		g.grounded = true
+		// whole milliseconds, like delayFrom, as replays record times
+		g.groundedAt = g.clock.elapsed().Truncate(time.Millisecond)
+	}
+	return g.clock.elapsed()-g.groundedAt >= time.Duration(g.Mode.LockDelay)*time.Millisecond
+}
+
+// FallInterval returns the time between gravity steps for the mode and current level.
+// A mode's gravity table takes precedence over base and FallSpeed.
+func (g *Game) FallInterval(base time.Duration) time.Duration {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if n := len(g.Mode.Gravity); n > 0 {
+		return time.Duration(g.Mode.Gravity[min(g.Level, n)-1]) * time.Millisecond
+	}
+	d := base / time.Duration(max(g.Mode.FallSpeed, 1))
+	// each level falls 15% faster than the one before
+	for i := 1; i < g.Level; i++ {
+		d = d * 85 / 100
+	}
+	return max(d, minFallInterval)
+}
@@ -0,0 +1,88 @@
+package model
+
+import "time"
+
+// now is the time source for game clocks
+var now = time.Now
+
+// clock measures play time, excluding time spent paused. It follows the
+// wall clock, or in frame mode only moves when advanced.
+type clock struct {
+	running bool
+	frames  bool // advanced by advance instead of the wall clock
+	since   time.Time
+	total   time.Duration
+}
+
+// start resumes the clock if it is stopped
+func (c *clock) start() {
+	if c.running {
+		return
+	}
+	c.running = true
+	c.since = now()
+}
+
+// stop freezes the clock, keeping the time accumulated so far
+func (c *clock) stop() {
+	if !c.running {
+		return
+	}
+	c.total = c.elapsed()
+	c.running = false
+}
+
+// elapsed returns the play time so far
+func (c *clock) elapsed() time.Duration {
+	if !c.running || c.frames {
+		return c.total
+	}
+	return c.total + now().Sub(c.since)
+}
+
+// set moves the clock to d of play time, leaving it running or stopped
+func (c *clock) set(d time.Duration) {
+	c.total = d
+	if c.running {
+		c.since = now()
+	}
+}
+
+// advance moves a clock in frame mode on by d, unless it is stopped
+func (c *clock) advance(d time.Duration) {
+	if c.running && c.frames {
+		c.total += d
+	}
+}
+
+// PlayTime returns the play time so far
+func (g *Game) PlayTime() time.Duration {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return g.clock.elapsed()
+}
+
+// UseFrameClock makes play time move only through Advance, so a game loop
+// can run the game in fixed frames however late its timer fires
+func (g *Game) UseFrameClock() {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.clock.total = g.clock.elapsed()
+	g.clock.frames = true
+}
+
+// Advance moves the play time of a game on a frame clock on by d. Paused
+// and finished games stand still.
+func (g *Game) Advance(d time.Duration) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.clock.advance(d)
+}
+
+// SetPlayTime moves the game clock to d of play time. Replays set it before
+// every recorded event so timers play out exactly as they did live.
+func (g *Game) SetPlayTime(d time.Duration) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.clock.set(d)
+}
@@ -0,0 +1,154 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"io/fs"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// TokenHeader carries the access token on API requests from clients that
+// are not pages served by the backend, such as bots and the terminal client.
+// The token may also be sent as ?token= or as "Authorization: Bearer".
+const TokenHeader = "X-Tetris-Token"
+
+// tokenParam is the query parameter the token is accepted in, which is also
+// how a browser gets it: the page address the backend logs includes it
+const tokenParam = "token"
+
+// NewToken returns a random access token, made once per launch
+func NewToken() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Token returns the token clients must present, "" when none is required
+func (s *Server) Token() string {
+	return s.token
+}
+
+// PageURL returns the address to open the frontend at, including the token
+// so the browser is let in, or "" when the server is not listening
+func (s *Server) PageURL() string {
+	u := s.URL()
+	if u == "" || s.token == "" {
+		return u
+	}
+	return u + "/?" + tokenParam + "=" + s.token
+}
+
+// checkOrigin lets in clients that are not browsers, which send no Origin,
+// pages served by the backend itself and the configured origins. Any other
+// page open in a browser is refused, so it cannot play or post scores.
+func (s *Server) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	return slices.Contains(s.origins, origin)
+}
+
+// authorized reports whether r carries the access token, by header, query
+// parameter or the cookie set when the frontend was opened
+func (s *Server) authorized(r *http.Request) bool {
+	if s.token == "" {
+		return true
+	}
+	given := []string{
+		r.Header.Get(TokenHeader),
+		strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "),
+		r.URL.Query().Get(tokenParam),
+	}
+	if c, err := r.Cookie(s.cookieName()); err == nil {
+		given = append(given, c.Value)
+	}
+	for _, t := range given {
+		if subtle.ConstantTimeCompare([]byte(t), []byte(s.token)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// cookieName includes the port, as cookies are shared by every port of a
+// host and each running backend has its own token
+func (s *Server) cookieName() string {
+	_, port, _ := net.SplitHostPort(s.Addr())
+	return "tetris_token_" + port
+}
+
+// guard wraps an API handler with the origin check, CORS for allowed
+// origins, the token check, the per-client rate limit and the body size limit
+func (s *Server) guard(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.checkOrigin(r) {
+			slog.Warn("Refused request from origin", "path", r.URL.Path, "origin", r.Header.Get("Origin"))
+			writeError(w, http.StatusForbidden, newAPIError("forbidden_origin", "origin not allowed"))
+			return
+		}
+		if origin := r.Header.Get("Origin"); origin != "" {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, "+TokenHeader)
+			w.Header().Add("Vary", "Origin")
+		}
+		// preflight requests never carry the token
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		if !s.authorized(r) {
+			writeError(w, http.StatusUnauthorized, newAPIError("unauthorized", "missing or wrong access token"))
+			return
+		}
+		if ok, wait := s.apiLimits.allow(r); !ok {
+			err := newAPIError("rate_limited", "too many requests")
+			err.RetryAfter = max(wait.Milliseconds(), 1)
+			writeError(w, http.StatusTooManyRequests, err)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, maxBodySize)
+		h(w, r)
+	}
+}
+
+// assetsHandler serves the frontend to anyone, as the pages hold nothing
+// secret. Opened with the token in the address, it hands the browser a
+// cookie so the pages' API calls and websockets are let in, then drops the
+// token from the address bar.
+func (s *Server) assetsHandler(assets fs.FS) http.Handler {
+	files := http.FileServerFS(assets)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if s.token == "" || !q.Has(tokenParam) {
+			files.ServeHTTP(w, r)
+			return
+		}
+		if !s.authorized(r) {
+			http.Error(w, "wrong access token", http.StatusUnauthorized)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{
+			Name:     s.cookieName(),
+			Value:    s.token,
+			Path:     "/",
+			HttpOnly: true,
+			SameSite: http.SameSiteStrictMode,
+		})
+		q.Del(tokenParam)
+		u := *r.URL
+		u.RawQuery = q.Encode()
+		http.Redirect(w, r, u.String(), http.StatusSeeOther)
+	})
+}
@@ -0,0 +1,123 @@
+package server
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"tetris-desktop/backend/ai"
+	"tetris-desktop/backend/model"
+)
+
+// AIBot returns the bot for a difficulty level, or the default bot when
+// level is empty or unknown
+func AIBot(level string) *ai.Bot {
+	if b, ok := ai.Level(level); ok {
+		return b
+	}
+	return ai.New()
+}
+
+// aiFromRequest builds a bot from the level, pps and lookahead query
+// parameters; pps and lookahead override the level's
+func aiFromRequest(r *http.Request) *ai.Bot {
+	q := r.URL.Query()
+	b := AIBot(q.Get("level"))
+	if pps, err := strconv.ParseFloat(q.Get("pps"), 64); err == nil && pps > 0 {
+		b.PPS = pps
+	}
+	if n, err := strconv.Atoi(q.Get("lookahead")); err == nil {
+		b.LookAhead = n
+	}
+	return b
+}
+
+// AIHandler streams a game played by the built-in AI over a websocket
+func (s *Server) AIHandler(w http.ResponseWriter, r *http.Request) {
+	log := slog.With("session", newSessionID(), "remote", r.RemoteAddr)
+	conn, stopPings, err := s.upgrade(w, r)
+	if err != nil {
+		log.Warn("AI websocket upgrade failed", "err", err)
+		return
+	}
+	defer conn.Close()
+	defer stopPings()
+
+	s.RunAI(wsSession{conn}, aiFromRequest(r), SessionOptions{
+		Mode: s.getModeFromSessionOrDefault(r),
+		Log:  log,
+	})
+}
+
+// RunAI streams games played by bot to one client, for a versus opponent
+// next to the player's board or as a demo in attract mode, until its
+// connection fails. The client can send "pause/resume" and "restart" like
+// a game session; everything else is ignored. Only opts.Mode and Log are
+// used.
+func (s *Server) RunAI(conn SessionConn, bot *ai.Bot, opts SessionOptions) {
+	if c, ok := conn.(io.Closer); ok {
+		defer s.track(c)()
+	}
+	defer s.metrics.session("ai")()
+	log := opts.Log
+	if log == nil {
+		log = slog.With("session", newSessionID())
+	}
+
+	var writeMu sync.Mutex
+	send := func(g *model.Game) error {
+		state := g.Snapshot()
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		return conn.WriteJSON(&state)
+	}
+
+	// play runs the bot on g until stop is closed
+	play := func(g *model.Game) chan struct{} {
+		stop := make(chan struct{})
+		go bot.Play(g, stop, func() { send(g) })
+		return stop
+	}
+
+	g := model.NewGame(opts.Mode)
+	log.Info("Starting AI game", "mode", g.Mode.ID, "pps", bot.PPS, "lookahead", bot.LookAhead)
+	defer log.Info("AI session closed")
+	stop := play(g)
+	defer func() { close(stop) }()
+	send(g)
+
+	limiter := newInputLimiter(inputRate, inputBurst)
+	for {
+		var msg wsMessage
+		if err := conn.ReadJSON(&msg); err != nil && !badJSON(err) {
+			readError(log, err)
+			return
+		}
+		s.metrics.message()
+		// other messages are ignored, so only flooding needs refusing
+		if ok, err := limiter.check(); !ok {
+			if err != nil {
+				writeMu.Lock()
+				conn.WriteJSON(err)
+				writeMu.Unlock()
+			}
+			continue
+		}
+		switch msg.Type {
+		case "pause/resume":
+			if g.TogglePause() {
+				send(g)
+			}
+		case "restart":
+			mode := opts.Mode
+			if msg.Mode != "" {
+				mode = s.modeByName(msg.Mode, msg.modeOptions)
+			}
+			close(stop)
+			g = model.NewGame(mode)
+			stop = play(g)
+			send(g)
+		}
+	}
+}
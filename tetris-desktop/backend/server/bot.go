@@ -0,0 +1,114 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"tetris-desktop/backend/model"
+)
+
+// botMessage is a request from an automated player. Place takes the chosen
+// placement either by its index in the last placements reply or by position.
+type botMessage struct {
+	Type        string `json:"type"`
+	Mode        string `json:"mode,omitempty"`
+	Index       *int   `json:"index,omitempty"`
+	X           int    `json:"x"`
+	Y           int    `json:"y"`
+	Orientation int    `json:"orientation"`
+	modeOptions
+}
+
+// botReply answers every bot request; State is always the state after it ran
+type botReply struct {
+	Type       string            `json:"type"`
+	State      *model.GameState  `json:"state"`
+	Placements []model.Placement `json:"placements,omitempty"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// BotHandler runs a game for an automated player over a websocket. There is
+// no gravity: the game only advances when the bot places a piece, so bots
+// can take as long as they like to think. Timed modes still end on the clock.
+//
+// Requests: {"type":"state"}, {"type":"placements"},
+// {"type":"place","index":n} or {"type":"place","x":..,"y":..,"orientation":..},
+// {"type":"hold"} and {"type":"restart","mode":..}.
+func (s *Server) BotHandler(w http.ResponseWriter, r *http.Request) {
+	log := slog.With("session", newSessionID(), "remote", r.RemoteAddr)
+	conn, stop, err := s.upgrade(w, r)
+	if err != nil {
+		log.Warn("Bot websocket upgrade failed", "err", err)
+		return
+	}
+	defer conn.Close()
+	defer stop()
+	defer s.metrics.session("bot")()
+	defer s.track(wsSession{conn})()
+
+	g := model.NewGame(s.getModeFromSessionOrDefault(r))
+	log.Info("Starting bot game", "mode", g.Mode.ID)
+	defer log.Info("Bot session closed")
+	var last []model.Placement
+	limiter := newInputLimiter(botRate, botBurst)
+
+	for {
+		var msg botMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			if !badJSON(err) {
+				readError(log, err)
+				return
+			}
+			conn.WriteJSON(newAPIError("bad_request", "invalid message: "+err.Error()))
+			continue
+		}
+		s.metrics.message()
+		if ok, err := limiter.check(); !ok {
+			if err != nil {
+				conn.WriteJSON(err)
+			}
+			continue
+		}
+		g.Expire()
+
+		reply := botReply{Type: msg.Type}
+		switch msg.Type {
+		case "state":
+		case "placements":
+			last = g.Placements()
+			reply.Placements = last
+		case "place":
+			p := model.Placement{X: msg.X, Y: msg.Y, Orientation: msg.Orientation}
+			if msg.Index != nil {
+				if *msg.Index < 0 || *msg.Index >= len(last) {
+					reply.Error = "no such placement"
+					break
+				}
+				p = last[*msg.Index]
+			}
+			if !g.Place(p) {
+				reply.Error = "illegal placement"
+			}
+			last = nil
+		case "hold":
+			if !g.Hold() {
+				reply.Error = "cannot hold"
+			}
+			last = nil
+		case "restart":
+			mode := s.getModeFromSessionOrDefault(r)
+			if msg.Mode != "" {
+				mode = s.modeByName(msg.Mode, msg.modeOptions)
+			}
+			g = model.NewGame(mode)
+			last = nil
+		default:
+			reply.Error = "unknown request"
+		}
+
+		state := g.Snapshot()
+		reply.State = &state
+		if err := conn.WriteJSON(&reply); err != nil {
+			return
+		}
+	}
+}
@@ -2,21 +2,60 @@ package server
 
 import (
 	"encoding/json"
+	"log/slog"
 	"net/http"
+	"slices"
+	"strconv"
 	"tetris-desktop/backend/model"
+	"time"
 )
 
+// modeOptions are the variations of a mode a client may choose.
+// Options the mode doesn't offer in its Choices are ignored.
+type modeOptions struct {
+	Lines     int     `json:"lines,omitempty"`     // line goal, or garbage rows in dig modes
+	Minutes   int     `json:"minutes,omitempty"`   // time limit
+	Messiness float64 `json:"messiness,omitempty"` // garbage messiness, 0..1
+}
+
 // getModeFromSessionOrDefault returns a GameMode based on query or default
 func (s *Server) getModeFromSessionOrDefault(r *http.Request) model.GameMode {
-	mode := r.URL.Query().Get("mode")
-	switch mode {
-	case "classic":
-		return s.ClassicMode
-	case "beginner":
-		fallthrough
-	default:
-		return s.BeginnerMode
+	q := r.URL.Query()
+	var opts modeOptions
+	opts.Lines, _ = strconv.Atoi(q.Get("lines"))
+	opts.Minutes, _ = strconv.Atoi(q.Get("minutes"))
+	opts.Messiness, _ = strconv.ParseFloat(q.Get("messiness"), 64)
+	return s.modeByName(q.Get("mode"), opts)
+}
+
+// modeByName resolves a mode id as sent by clients, falling back to the default mode
+func (s *Server) modeByName(name string, opts modeOptions) model.GameMode {
+	set := s.Modes()
+	mode, ok := set.find(name)
+	if !ok {
+		mode = set.fallback()
 	}
+	if slices.Contains(mode.Choices.Lines, opts.Lines) {
+		if mode.Goal.Garbage {
+			mode.Garbage.Total = opts.Lines
+			mode.Garbage.Rows = min(mode.Garbage.Rows, opts.Lines)
+		} else {
+			mode.Goal.Lines = opts.Lines
+		}
+	}
+	if slices.Contains(mode.Choices.Minutes, opts.Minutes) {
+		mode.Goal.TimeLimit = (time.Duration(opts.Minutes) * time.Minute).Milliseconds()
+	}
+	if mode.Goal.Garbage && opts.Messiness > 0 && opts.Messiness <= 1 {
+		mode.Garbage.Messiness = opts.Messiness
+	}
+	return mode
+}
+
+// Mode resolves a mode id like a client would, taking the line goal and time
+// limit from the mode's choices when given, falling back to the default mode
+func (s *Server) Mode(id string, lines, minutes int) model.GameMode {
+	return s.modeByName(id, modeOptions{Lines: lines, Minutes: minutes})
 }
 
 // GetGameMode is an HTTP handler returning the chosen/default mode
@@ -25,14 +64,65 @@ func (s *Server) GetGameMode(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(mode)
 }
 
-// Helper to allow main to register handlers easily
+// handle registers an API endpoint behind the origin and token checks
+func (s *Server) handle(pattern string, h http.HandlerFunc) {
+	s.mux.HandleFunc(pattern, s.guard(h))
+}
+
+// RegisterHandlers adds the game and API endpoints to the server's mux
 func (s *Server) RegisterHandlers() {
-	http.HandleFunc("/ws", s.WSHandler)
-	http.HandleFunc("/getGameMode", s.GetGameMode)
+	s.handle("/ws", s.WSHandler)
+	s.handle("/getGameMode", s.GetGameMode)
+	s.handle("/modes", s.ModesHandler)
+	s.handle("/bot", s.BotHandler)
+	s.handle("/ai", s.AIHandler)
+	s.handle("/highscores", s.HighscoresHandler)
+	s.handle("/settings", s.SettingsHandler)
+	s.handle("/replays", s.ReplaysHandler)
+	s.handle("/saves", s.SavesHandler)
+	s.handle("/stats", s.StatsHandler)
+	// left open for scrapers and uptime checks, which hold no token
+	s.mux.HandleFunc("/healthz", s.HealthHandler)
+	s.mux.HandleFunc("/metrics", s.MetricsHandler)
+
+	s.handle("/start", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		slog.Debug("Game started")
+		w.WriteHeader(http.StatusOK)
+	})
+
+	s.handle("/restart", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"status":"ok"}`))
+		}
+	})
+
+	s.handle("/quit", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		slog.Info("Game quit")
+		w.WriteHeader(http.StatusOK)
+		if s.OnQuit != nil {
+			// let the response go out before the app starts closing
+			go s.OnQuit()
+		}
+	})
+}
+
+// Handler returns the handler serving everything registered on the server
+func (s *Server) Handler() http.Handler {
+	return s.mux
 }
 
 // Expose a restart message parsing helper
 type restartMsg struct {
 	Type string `json:"type"`
 	Mode string `json:"mode,omitempty"`
+	modeOptions
 }
@@ -0,0 +1,172 @@
+package server
+
+import (
+	"strconv"
+	"time"
+)
+
+// Handling is how held keys repeat, as the player set it in their profile
+type Handling struct {
+	DAS int `json:"das"` // milliseconds a direction is held before it repeats
+	ARR int `json:"arr"` // milliseconds between repeats; 0 moves to the wall at once
+	SDF int `json:"sdf"` // soft drop speed as a multiple of gravity; 0 drops to the floor at once
+}
+
+// DefaultHandling is used for settings the profile leaves out
+var DefaultHandling = Handling{DAS: 167, ARR: 33, SDF: 20}
+
+// the most each setting may be
+const (
+	maxDAS = 1000
+	maxARR = 500
+	maxSDF = 100
+)
+
+// Handling returns the handling stored in the profile's settings under
+// "das", "arr" and "sdf", with defaults for missing or invalid values
+func (s *Server) Handling() Handling {
+	set := s.Settings()
+	h := DefaultHandling
+	setting := func(key string, limit int, v *int) {
+		if n, err := strconv.Atoi(set[key]); err == nil && n >= 0 && n <= limit {
+			*v = n
+		}
+	}
+	setting("das", maxDAS, &h.DAS)
+	setting("arr", maxARR, &h.ARR)
+	setting("sdf", maxSDF, &h.SDF)
+	return h
+}
+
+// repeater turns key presses and releases into moves. A pressed direction
+// moves once, then again every ARR once it has been held for DAS; a held
+// soft drop moves down at SDF times gravity. Times are play time, so held
+// keys wait while the game is paused.
+type repeater struct {
+	h    Handling
+	move func(dir string, repeat bool) bool // performs and records one move; repeats are not new inputs
+
+	left, right bool          // sideways keys held
+	dir         string        // the sideways direction repeating, the latest pressed
+	shiftAt     time.Duration // when dir next moves
+	down        bool          // soft drop held
+	dropAt      time.Duration // when soft drop next moves
+}
+
+func newRepeater(h Handling, move func(dir string, repeat bool) bool) *repeater {
+	return &repeater{h: h, move: move}
+}
+
+// reset takes up handling h for a new game starting at play time now;
+// keys still held carry on from a fresh DAS
+func (r *repeater) reset(h Handling, now time.Duration) {
+	r.h = h
+	if r.dir != "" {
+		r.charge(r.dir, now)
+	}
+	r.dropAt = now
+}
+
+// press starts holding dir at play time now, moving once straight away.
+// It reports whether the move changed the game.
+func (r *repeater) press(dir string, now, fall time.Duration) bool {
+	switch dir {
+	case "left":
+		r.left = true
+	case "right":
+		r.right = true
+	case "down":
+		r.down = true
+		r.dropAt = now + r.softDrop(fall)
+		moved := r.move("down", false)
+		if r.h.SDF == 0 && r.toWall("down") {
+			moved = true
+		}
+		return moved
+	}
+	r.charge(dir, now)
+	return r.move(dir, false)
+}
+
+// release stops holding dir. Letting go of one sideways key while the
+// other is still held charges DAS again in that direction.
+func (r *repeater) release(dir string, now time.Duration) {
+	switch dir {
+	case "left":
+		r.left = false
+	case "right":
+		r.right = false
+	case "down":
+		r.down = false
+		return
+	}
+	if dir != r.dir {
+		return
+	}
+	r.dir = ""
+	if r.left {
+		r.charge("left", now)
+	} else if r.right {
+		r.charge("right", now)
+	}
+}
+
+// releaseAll lets go of every key, as if the client had released them
+func (r *repeater) releaseAll() {
+	r.left, r.right, r.down = false, false, false
+	r.dir = ""
+}
+
+// charge makes dir the repeating direction, first repeating after DAS
+func (r *repeater) charge(dir string, now time.Duration) {
+	r.dir = dir
+	r.shiftAt = now + time.Duration(r.h.DAS)*time.Millisecond
+}
+
+// update makes the moves held keys owe by play time now, for a game
+// falling one row per fall. It reports whether any of them changed the game.
+func (r *repeater) update(now, fall time.Duration) bool {
+	changed := false
+	if r.dir != "" {
+		if r.h.ARR == 0 {
+			if now >= r.shiftAt && r.toWall(r.dir) {
+				changed = true
+			}
+		} else {
+			for ; now >= r.shiftAt; r.shiftAt += time.Duration(r.h.ARR) * time.Millisecond {
+				if r.move(r.dir, true) {
+					changed = true
+				}
+			}
+		}
+	}
+	if r.down {
+		if r.h.SDF == 0 {
+			if r.toWall("down") {
+				changed = true
+			}
+		} else {
+			for step := r.softDrop(fall); now >= r.dropAt; r.dropAt += step {
+				if r.move("down", true) {
+					changed = true
+				}
+			}
+		}
+	}
+	return changed
+}
+
+// toWall repeats dir as far as the piece goes
+func (r *repeater) toWall(dir string) bool {
+	changed := false
+	for r.move(dir, true) {
+		changed = true
+	}
+	return changed
+}
+
+// softDrop returns the time between soft drop moves for a game falling
+// one row per fall
+func (r *repeater) softDrop(fall time.Duration) time.Duration {
+	return max(fall/time.Duration(max(r.h.SDF, 1)), time.Millisecond)
+}
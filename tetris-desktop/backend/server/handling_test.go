@@ -0,0 +1,102 @@
+package server
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRepeater(t *testing.T) {
+	const fall = time.Second
+	ms := func(n int) time.Duration { return time.Duration(n) * time.Millisecond }
+	type key struct {
+		at     int // play time in milliseconds
+		action string
+		dir    string
+	}
+	tests := []struct {
+		name  string
+		h     Handling
+		keys  []key
+		until int // update at this play time after the keys
+		moves []string
+	}{
+		{
+			name:  "tap",
+			h:     Handling{DAS: 100, ARR: 50, SDF: 20},
+			keys:  []key{{0, "press", "left"}, {50, "release", "left"}},
+			until: 500,
+			moves: []string{"left"},
+		},
+		{
+			name:  "held past DAS",
+			h:     Handling{DAS: 100, ARR: 50, SDF: 20},
+			keys:  []key{{0, "press", "right"}},
+			until: 200,
+			moves: []string{"right", "right*", "right*", "right*"},
+		},
+		{
+			name:  "ARR 0 goes to the wall",
+			h:     Handling{DAS: 100, ARR: 0, SDF: 20},
+			keys:  []key{{0, "press", "left"}},
+			until: 100,
+			moves: []string{"left", "left*", "left*", "left*"},
+		},
+		{
+			name:  "other key still held charges DAS again",
+			h:     Handling{DAS: 100, ARR: 50, SDF: 20},
+			keys:  []key{{0, "press", "left"}, {80, "press", "right"}, {90, "release", "right"}},
+			until: 189,
+			moves: []string{"left", "right"},
+		},
+		{
+			name:  "soft drop at SDF times gravity",
+			h:     Handling{DAS: 100, ARR: 50, SDF: 20},
+			keys:  []key{{0, "press", "down"}},
+			until: 100,
+			moves: []string{"down", "down*", "down*"},
+		},
+		{
+			name:  "dropped message lets go of everything",
+			h:     Handling{DAS: 100, ARR: 50, SDF: 20},
+			keys:  []key{{0, "press", "left"}, {0, "press", "down"}, {10, "releaseAll", ""}},
+			until: 500,
+			moves: []string{"left", "down"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var moves []string
+			wall := 3 // sideways moves left before the piece stops
+			r := newRepeater(tt.h, func(dir string, repeat bool) bool {
+				if dir != "down" && repeat {
+					if wall == 0 {
+						return false
+					}
+					wall--
+				}
+				if repeat {
+					dir += "*"
+				}
+				moves = append(moves, dir)
+				return true
+			})
+			r.reset(tt.h, 0)
+			for _, k := range tt.keys {
+				r.update(ms(k.at), fall)
+				switch k.action {
+				case "press":
+					r.press(k.dir, ms(k.at), fall)
+				case "release":
+					r.release(k.dir, ms(k.at))
+				case "releaseAll":
+					r.releaseAll()
+				}
+			}
+			r.update(ms(tt.until), fall)
+			if !slices.Equal(moves, tt.moves) {
+				t.Errorf("moves %v, want %v", moves, tt.moves)
+			}
+		})
+	}
+}
@@ -0,0 +1,266 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"tetris-desktop/backend/model"
+	"time"
+)
+
+// Highscore entry
+type Highscore struct {
+	Name    string    `json:"name"`
+	Score   int       `json:"score"`
+	Mode    string    `json:"mode,omitempty"`
+	Lines   int       `json:"lines,omitempty"`
+	Minutes int       `json:"minutes,omitempty"`
+	Time    int64     `json:"time,omitempty"`   // milliseconds, for timed modes
+	Pieces  int       `json:"pieces,omitempty"` // tie-break for timed modes
+	When    time.Time `json:"when"`
+	Replay  string    `json:"replay,omitempty"` // id of the game's replay
+}
+
+// maxHS is the number of entries kept per leaderboard
+const maxHS = 10
+
+// board identifies one leaderboard: a mode and the goal it was played to
+type board struct {
+	Mode    string
+	Lines   int
+	Minutes int
+}
+
+// boardOf normalises a mode and its goal to the leaderboard they are ranked
+// on. Endless modes, with no goal to play to, share the original leaderboard.
+func (s *Server) boardOf(mode string, lines, minutes int) board {
+	if m, ok := s.Modes().find(mode); mode == "" || ok && m.Endless() {
+		return board{}
+	}
+	return board{Mode: mode, Lines: lines, Minutes: minutes}
+}
+
+// board returns the leaderboard an entry is ranked on
+func (h Highscore) board() board {
+	return board{Mode: h.Mode, Lines: h.Lines, Minutes: h.Minutes}
+}
+
+// rankBefore orders two entries of the same leaderboard
+func (s *Server) rankBefore(a, b Highscore) bool {
+	if s.RankedByTime(a.Mode) {
+		if a.Time == b.Time {
+			return a.Pieces < b.Pieces
+		}
+		return a.Time < b.Time
+	}
+	return a.Score > b.Score
+}
+
+// boardEntries returns the entries of one leaderboard in ranking order
+func boardEntries(all []Highscore, b board) []Highscore {
+	out := []Highscore{}
+	for _, h := range all {
+		if h.board() == b {
+			out = append(out, h)
+		}
+	}
+	return out
+}
+
+// insertHighscore adds e to its leaderboard and keeps the top maxHS of that board
+func (s *Server) insertHighscore(all []Highscore, e Highscore) []Highscore {
+	rest := make([]Highscore, 0, len(all))
+	for _, h := range all {
+		if h.board() != e.board() {
+			rest = append(rest, h)
+		}
+	}
+	ranked := append(boardEntries(all, e.board()), e)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return s.rankBefore(ranked[i], ranked[j])
+	})
+	if len(ranked) > maxHS {
+		ranked = ranked[:maxHS]
+	}
+	return append(rest, ranked...)
+}
+
+// LoadHighscores reads the highscores kept in path and saves new ones there.
+// A missing or unreadable file starts an empty list.
+func (s *Server) LoadHighscores(path string) {
+	s.hsMu.Lock()
+	defer s.hsMu.Unlock()
+	s.hsFile = path
+	s.highscores = []Highscore{}
+	slog.Info("Highscores file", "path", path)
+
+	f, err := os.Open(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			slog.Error("Highscores not loaded", "path", path, "err", err)
+			s.metrics.highscoreError("load")
+		}
+		return
+	}
+	defer f.Close()
+	dec := json.NewDecoder(f)
+	var hs []Highscore
+	if err := dec.Decode(&hs); err != nil && err != io.EOF {
+		slog.Error("Highscores not loaded", "path", path, "err", err)
+		s.metrics.highscoreError("load")
+		return
+	}
+	if hs != nil {
+		s.highscores = hs
+	}
+}
+
+// saveHighscores writes the highscores to their file; callers hold hsMu
+func (s *Server) saveHighscores() {
+	if s.hsFile == "" {
+		return
+	}
+	tmp := s.hsFile + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		slog.Error("Highscores not saved", "path", s.hsFile, "err", err)
+		s.metrics.highscoreError("save")
+		return
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(s.highscores); err != nil {
+		slog.Error("Highscores not saved", "path", s.hsFile, "err", err)
+		s.metrics.highscoreError("save")
+		f.Close()
+		return
+	}
+	f.Close()
+	if err := os.Rename(tmp, s.hsFile); err != nil {
+		slog.Error("Highscores not saved", "path", s.hsFile, "err", err)
+		s.metrics.highscoreError("save")
+	}
+}
+
+// ScoreSubmission offers a finished game for a leaderboard. The game is
+// named by its replay id, sent in the game's last state; its score, time
+// and pieces are taken from the replay, not the client.
+type ScoreSubmission struct {
+	Name    string `json:"name"`
+	Mode    string `json:"mode"`
+	Lines   int    `json:"lines"`
+	Minutes int    `json:"minutes"`
+	Replay  string `json:"replay"`
+}
+
+// reasons a submission is turned down
+var (
+	ErrNoGame        = errors.New("no finished game with that replay id")
+	ErrAlreadyRanked = errors.New("game already submitted")
+	ErrWrongBoard    = errors.New("game was not played to that leaderboard's mode and goal")
+	ErrTimeRequired  = errors.New("timed modes only rank completed games")
+)
+
+// Highscores returns one leaderboard in ranking order
+func (s *Server) Highscores(mode string, lines, minutes int) []Highscore {
+	s.hsMu.Lock()
+	defer s.hsMu.Unlock()
+	return boardEntries(s.highscores, s.boardOf(mode, lines, minutes))
+}
+
+// AddHighscore ranks a submission on its leaderboard and saves the boards
+func (s *Server) AddHighscore(req ScoreSubmission) error {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = "Anonymous"
+	}
+	if len(name) > 20 {
+		name = name[:20]
+	}
+	rec, err := s.Replay(req.Replay)
+	if err != nil || !rec.Ended {
+		return ErrNoGame
+	}
+	b := s.boardOf(req.Mode, req.Lines, req.Minutes)
+	if !playedOn(rec, req.Mode, b, s.Mode(req.Mode, req.Lines, req.Minutes)) {
+		return ErrWrongBoard
+	}
+	entry := Highscore{
+		Name:    name,
+		Score:   rec.Score,
+		Mode:    b.Mode,
+		Lines:   b.Lines,
+		Minutes: b.Minutes,
+		When:    time.Now().UTC(),
+		Replay:  rec.ID,
+	}
+	if s.RankedByTime(b.Mode) {
+		if !rec.Completed {
+			return ErrTimeRequired
+		}
+		entry.Time = rec.Elapsed
+		entry.Pieces = rec.Pieces
+	}
+
+	s.hsMu.Lock()
+	defer s.hsMu.Unlock()
+	if slices.ContainsFunc(s.highscores, func(h Highscore) bool { return h.Replay == rec.ID }) {
+		return ErrAlreadyRanked
+	}
+	// insert into its board and keep that board ranked, then persist
+	s.highscores = s.insertHighscore(s.highscores, entry)
+	s.saveHighscores()
+	return nil
+}
+
+// playedOn reports whether the recorded game belongs on leaderboard b, as
+// submitted for mode id. The endless board takes games of endless modes,
+// of mode id when one is named; any other board only games of its mode
+// played to the same goal as mode, the mode b resolves to.
+func playedOn(rec *Replay, id string, b board, mode model.GameMode) bool {
+	if b == (board{}) {
+		return rec.Mode.Endless() && (id == "" || rec.Mode.ID == id)
+	}
+	return rec.Mode.ID == b.Mode && rec.Mode.Goal == mode.Goal &&
+		rec.Mode.Garbage.Total == mode.Garbage.Total
+}
+
+// HighscoresHandler serves GET /highscores and POST /highscores
+func (s *Server) HighscoresHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		q := r.URL.Query()
+		lines, _ := strconv.Atoi(q.Get("lines"))
+		minutes, _ := strconv.Atoi(q.Get("minutes"))
+		out := s.Highscores(q.Get("mode"), lines, minutes)
+		slog.Debug("Highscores listed", "mode", q.Get("mode"), "entries", len(out))
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(out)
+		return
+
+	case http.MethodPost:
+		var req ScoreSubmission
+		if !decodeBody(w, r, &req) {
+			return
+		}
+		slog.Debug("Highscore submitted", "name", req.Name, "mode", req.Mode, "replay", req.Replay)
+		if err := s.AddHighscore(req); err != nil {
+			writeError(w, http.StatusBadRequest, newAPIError("bad_request", err.Error()))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"ok": true})
+		return
+	default:
+		writeError(w, http.StatusMethodNotAllowed, newAPIError("method_not_allowed", "method not allowed"))
+		return
+	}
+}
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestAddHighscore(t *testing.T) {
+	s := New()
+	s.dataDir = t.TempDir()
+	games := map[string]*Replay{}
+	finished := func(id, mode string, lines int, completed bool) {
+		m := s.Mode(mode, lines, 3)
+		r := &Replay{ID: id, Mode: m, Score: 1000, Lines: 40, Pieces: 100, Elapsed: 60000, Ended: true, Completed: completed}
+		if err := s.finishReplay(r); err != nil {
+			t.Fatal(err)
+		}
+		games[id] = r
+	}
+	finished("endless", "classic", 0, false)
+	finished("sprint-40", "sprint", 40, true)
+	finished("sprint-topped-out", "sprint", 40, false)
+	finished("ultra-3", "ultra", 0, true)
+	finished("classic-timing", "classic-timing", 0, false)
+	if err := s.writeReplay(savesDir, &Replay{ID: "unfinished", Mode: s.Mode("classic", 0, 0)}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		req  ScoreSubmission
+		want error
+	}{
+		{"endless", ScoreSubmission{Name: "a", Mode: "classic", Replay: "endless"}, nil},
+		{"endless again", ScoreSubmission{Name: "a", Mode: "classic", Replay: "endless"}, ErrAlreadyRanked},
+		{"other endless mode", ScoreSubmission{Name: "a", Mode: "beginner", Replay: "endless"}, ErrWrongBoard},
+		{"endless from modes file", ScoreSubmission{Name: "a", Replay: "classic-timing"}, nil},
+		{"timed on the endless board", ScoreSubmission{Name: "a", Replay: "ultra-3"}, ErrWrongBoard},
+		{"timed as an endless mode", ScoreSubmission{Name: "a", Mode: "classic", Replay: "ultra-3"}, ErrWrongBoard},
+		{"no replay", ScoreSubmission{Name: "a", Mode: "classic"}, ErrNoGame},
+		{"unknown replay", ScoreSubmission{Name: "a", Mode: "classic", Replay: "nope"}, ErrNoGame},
+		{"saved not finished", ScoreSubmission{Name: "a", Mode: "classic", Replay: "unfinished"}, ErrNoGame},
+		{"bad id", ScoreSubmission{Name: "a", Mode: "classic", Replay: "../highscores"}, ErrNoGame},
+		{"other goal", ScoreSubmission{Name: "a", Mode: "sprint", Lines: 20, Replay: "sprint-40"}, ErrWrongBoard},
+		{"other mode", ScoreSubmission{Name: "a", Mode: "ultra", Minutes: 3, Replay: "sprint-40"}, ErrWrongBoard},
+		{"timed not endless", ScoreSubmission{Name: "a", Mode: "classic", Replay: "sprint-40"}, ErrWrongBoard},
+		{"timed not completed", ScoreSubmission{Name: "a", Mode: "sprint", Lines: 40, Replay: "sprint-topped-out"}, ErrTimeRequired},
+		{"timed", ScoreSubmission{Name: "b", Mode: "sprint", Lines: 40, Replay: "sprint-40"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := s.AddHighscore(tt.req); !errors.Is(err, tt.want) {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	// scores come from the replays, whatever the client claims
+	for _, h := range append(s.Highscores("classic", 0, 0), s.Highscores("sprint", 40, 0)...) {
+		r := games[h.Replay]
+		got := fmt.Sprint(h.Score, h.Time, h.Pieces)
+		want := fmt.Sprint(r.Score, 0, 0)
+		if r.Mode.Ranking == "time" {
+			want = fmt.Sprint(r.Score, r.Elapsed, r.Pieces)
+		}
+		if got != want {
+			t.Errorf("%s ranked with score, time, pieces %s, want %s", h.Replay, got, want)
+		}
+	}
+	if n := len(s.Highscores("classic", 0, 0)) + len(s.Highscores("sprint", 40, 0)); n != 3 {
+		t.Errorf("%d entries ranked, want 3", n)
+	}
+}
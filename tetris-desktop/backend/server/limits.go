@@ -0,0 +1,232 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Limits on what a client may send. Inputs are limited per connection;
+// other API requests per client address.
+const (
+	maxMessageSize = 4 << 10  // bytes in one websocket message
+	maxBodySize    = 64 << 10 // bytes in one request body
+
+	inputRate  = 60 // game inputs per second, well above any player's
+	inputBurst = 30
+	botRate    = 500 // bot requests per second, which cost a placement search
+	botBurst   = 100
+	apiRate    = 20 // other API requests per second from one address
+	apiBurst   = 40
+
+	pongWait   = 60 * time.Second  // a connection silent this long is dropped
+	pingPeriod = pongWait * 9 / 10 // how often the server asks for a pong
+	writeWait  = 10 * time.Second  // time allowed for a ping to go out
+	apiIdle    = 10 * time.Minute  // client addresses unseen this long are forgotten
+	apiClients = 1000              // most client addresses remembered at once
+	readHeader = 10 * time.Second  // time allowed for a request's headers
+	errorEvery = time.Second       // at most one rate limit error per connection this often
+)
+
+// apiError is the body of every refused request, and the message a
+// websocket client gets when one of its messages is refused
+type apiError struct {
+	Type       string `json:"type"` // always "error"
+	Code       string `json:"code"` // machine readable, e.g. "rate_limited"
+	Error      string `json:"error"`
+	RetryAfter int64  `json:"retryAfter,omitempty"` // milliseconds until it is worth trying again
+}
+
+func newAPIError(code, msg string) *apiError {
+	return &apiError{Type: "error", Code: code, Error: msg}
+}
+
+// writeError sends err with status as a JSON body
+func writeError(w http.ResponseWriter, status int, err *apiError) {
+	if err.RetryAfter > 0 {
+		// whole seconds, rounded up
+		w.Header().Set("Retry-After", strconv.FormatInt((err.RetryAfter+999)/1000, 10))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(err)
+}
+
+// decodeBody reads a JSON request body into v, answering the client
+// itself and returning false when the body is too large or malformed
+func decodeBody(w http.ResponseWriter, r *http.Request, v any) bool {
+	err := json.NewDecoder(r.Body).Decode(v)
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		writeError(w, http.StatusRequestEntityTooLarge,
+			newAPIError("too_large", fmt.Sprintf("request body over %d bytes", tooLarge.Limit)))
+		return false
+	case err != nil:
+		writeError(w, http.StatusBadRequest, newAPIError("bad_request", "invalid body: "+err.Error()))
+		return false
+	}
+	return true
+}
+
+// badJSON reports whether a read failed only because the message was not
+// the JSON expected, which leaves the connection usable
+func badJSON(err error) bool {
+	var syntax *json.SyntaxError
+	var typ *json.UnmarshalTypeError
+	return errors.As(err, &syntax) || errors.As(err, &typ)
+}
+
+// rateLimiter is a token bucket: it allows burst events at once and rate
+// events per second after that
+type rateLimiter struct {
+	rate, burst float64
+	tokens      float64
+	last        time.Time
+}
+
+func newRateLimiter(rate, burst int) *rateLimiter {
+	return &rateLimiter{rate: float64(rate), burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// allow takes one event from the bucket, or reports how long until one is
+// available
+func (l *rateLimiter) allow(now time.Time) (bool, time.Duration) {
+	l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	if l.tokens >= 1 {
+		l.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+	return false, wait
+}
+
+// inputLimiter limits the messages of one connection and tells the client
+// when some were dropped, but not for every one of a flood
+type inputLimiter struct {
+	bucket   *rateLimiter
+	lastWarn time.Time
+}
+
+func newInputLimiter(rate, burst int) *inputLimiter {
+	return &inputLimiter{bucket: newRateLimiter(rate, burst)}
+}
+
+// check reports whether a message may be handled. When it may not, it
+// also returns the error to send the client, or nil if one went out recently.
+func (l *inputLimiter) check() (bool, *apiError) {
+	now := time.Now()
+	ok, wait := l.bucket.allow(now)
+	if ok {
+		return true, nil
+	}
+	if now.Sub(l.lastWarn) < errorEvery {
+		return false, nil
+	}
+	l.lastWarn = now
+	err := newAPIError("rate_limited", "too many messages, some were dropped")
+	err.RetryAfter = max(wait.Milliseconds(), 1)
+	return false, err
+}
+
+// clientLimits keeps a rate limiter per client address for the HTTP API
+type clientLimits struct {
+	mu      sync.Mutex
+	clients map[string]*rateLimiter
+}
+
+// allow reports whether the client behind r may make another request, or
+// how long it should wait
+func (c *clientLimits) allow(r *http.Request) (bool, time.Duration) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.clients == nil {
+		c.clients = map[string]*rateLimiter{}
+	}
+	l := c.clients[host]
+	if l == nil {
+		if len(c.clients) >= apiClients {
+			c.forget(now)
+		}
+		l = newRateLimiter(apiRate, apiBurst)
+		c.clients[host] = l
+	}
+	return l.allow(now)
+}
+
+// forget drops the clients idle too long, or the one seen longest ago when
+// none are, so the table never grows past apiClients
+func (c *clientLimits) forget(now time.Time) {
+	oldest := ""
+	for h, l := range c.clients {
+		if now.Sub(l.last) > apiIdle {
+			delete(c.clients, h)
+		} else if oldest == "" || l.last.Before(c.clients[oldest].last) {
+			oldest = h
+		}
+	}
+	if len(c.clients) >= apiClients {
+		delete(c.clients, oldest)
+	}
+}
+
+// upgrade opens a websocket with the message size limit and read deadline
+// set, and keeps it alive with pings. The returned function stops the pings
+// and must be called when the connection is done.
+func (s *Server) upgrade(w http.ResponseWriter, r *http.Request) (*websocket.Conn, func(), error) {
+	conn, err := s.Upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	conn.SetReadLimit(maxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(pingPeriod)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait)); err != nil {
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return conn, func() { once.Do(func() { close(done) }) }, nil
+}
+
+// readError logs why a websocket stopped being read, such as a message
+// over the size limit or a client that stopped answering pings, unless the
+// client simply went away
+func readError(log *slog.Logger, err error) {
+	if websocket.IsCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure, websocket.CloseNoStatusReceived) ||
+		errors.Is(err, net.ErrClosed) || errors.Is(err, io.EOF) {
+		return
+	}
+	log.Info("Connection dropped", "err", err)
+}
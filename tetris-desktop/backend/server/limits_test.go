@@ -0,0 +1,86 @@
+package server
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	l := newRateLimiter(10, 3)
+	start := l.last
+	tests := []struct {
+		after time.Duration // since the limiter was made
+		ok    bool
+		wait  time.Duration
+	}{
+		{0, true, 0},
+		{0, true, 0},
+		{0, true, 0},
+		{0, false, 100 * time.Millisecond}, // burst used up
+		{50 * time.Millisecond, false, 50 * time.Millisecond},
+		{100 * time.Millisecond, true, 0}, // one refilled
+		{100 * time.Millisecond, false, 100 * time.Millisecond},
+		{time.Hour, true, 0}, // refilled to the burst, no more
+		{time.Hour, true, 0},
+		{time.Hour, true, 0},
+		{time.Hour, false, 100 * time.Millisecond},
+	}
+	for i, tt := range tests {
+		ok, wait := l.allow(start.Add(tt.after))
+		if ok != tt.ok || (wait-tt.wait).Abs() > time.Millisecond {
+			t.Errorf("event %d at %v: got %v, wait %v; want %v, wait %v", i, tt.after, ok, wait, tt.ok, tt.wait)
+		}
+	}
+}
+
+func TestInputLimiter(t *testing.T) {
+	l := newInputLimiter(1, 2)
+	for i := range 2 {
+		if ok, err := l.check(); !ok || err != nil {
+			t.Fatalf("message %d within the burst refused: %v", i, err)
+		}
+	}
+	ok, err := l.check()
+	if ok || err == nil || err.Code != "rate_limited" || err.RetryAfter <= 0 {
+		t.Fatalf("first message over the limit: got %v, %+v", ok, err)
+	}
+	// the client was just told, so the next drops go quietly
+	if ok, err := l.check(); ok || err != nil {
+		t.Fatalf("second message over the limit: got %v, %+v", ok, err)
+	}
+}
+
+func TestClientLimitsCap(t *testing.T) {
+	var c clientLimits
+	request := func(i int) {
+		t.Helper()
+		r := httptest.NewRequest("GET", "/modes", nil)
+		r.RemoteAddr = fmt.Sprintf("10.0.%d.%d:1234", i/256, i%256)
+		if ok, _ := c.allow(r); !ok {
+			t.Fatalf("first request from client %d refused", i)
+		}
+	}
+	for i := range apiClients {
+		request(i)
+	}
+	// none idle, so the one seen longest ago makes way
+	c.clients["10.0.0.7"].last = time.Now().Add(-time.Minute)
+	request(apiClients)
+	if len(c.clients) != apiClients {
+		t.Fatalf("remembering %d clients, want at most %d", len(c.clients), apiClients)
+	}
+	if c.clients["10.0.0.7"] != nil {
+		t.Error("client seen longest ago kept")
+	}
+
+	// idle ones are all forgotten
+	for _, h := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"} {
+		c.clients[h].last = time.Now().Add(-apiIdle - time.Second)
+	}
+	request(apiClients + 1)
+	if len(c.clients) != apiClients-2 {
+		t.Errorf("remembering %d clients, want %d with the idle ones gone", len(c.clients), apiClients-2)
+	}
+}
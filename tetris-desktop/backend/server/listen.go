@@ -0,0 +1,123 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// PortRange is a span of ports to try when the configured one is taken
+type PortRange struct {
+	First, Last int
+}
+
+// ParsePortRange reads a range written as "8081-8090" or a single port
+func ParsePortRange(s string) (PortRange, error) {
+	first, last, found := strings.Cut(s, "-")
+	if !found {
+		last = first
+	}
+	a, err1 := strconv.Atoi(strings.TrimSpace(first))
+	b, err2 := strconv.Atoi(strings.TrimSpace(last))
+	if err1 != nil || err2 != nil || a < 1 || b > 65535 || a > b {
+		return PortRange{}, fmt.Errorf("invalid port range %q, want first-last", s)
+	}
+	return PortRange{a, b}, nil
+}
+
+func (p PortRange) String() string {
+	return fmt.Sprintf("%d-%d", p.First, p.Last)
+}
+
+// candidates returns the addresses to try in order: addr itself, then the
+// other ports of the range on the same host, or any free port without one
+func (p *PortRange) candidates(addr string) []string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return []string{addr}
+	}
+	out := []string{addr}
+	if p == nil {
+		return append(out, net.JoinHostPort(host, "0"))
+	}
+	for n := p.First; n <= p.Last; n++ {
+		if strconv.Itoa(n) != port {
+			out = append(out, net.JoinHostPort(host, strconv.Itoa(n)))
+		}
+	}
+	return out
+}
+
+// Listen opens the server's port. When the configured address is taken it
+// moves on to the configured port range, or to any free port if there is
+// none; Addr reports where it ended up.
+func (s *Server) Listen() error {
+	if s.listener != nil {
+		return nil
+	}
+	var first error
+	for _, addr := range s.ports.candidates(s.HTTPServer.Addr) {
+		l, err := net.Listen("tcp", addr)
+		if err == nil {
+			if first != nil {
+				slog.Warn("Address taken, using another", "addr", s.HTTPServer.Addr, "err", first, "using", l.Addr().String())
+			}
+			s.listener = l
+			return nil
+		}
+		if first == nil {
+			first = err
+		}
+	}
+	if s.ports != nil {
+		return fmt.Errorf("no free port for the backend: %s and ports %s are all in use or unavailable: %w",
+			s.HTTPServer.Addr, s.ports, first)
+	}
+	return fmt.Errorf("no free port for the backend: %w", first)
+}
+
+// Addr returns the address the server listens on once Listen succeeded,
+// or "" before
+func (s *Server) Addr() string {
+	if s.listener == nil {
+		return ""
+	}
+	return s.listener.Addr().String()
+}
+
+// URL returns the base URL of the HTTP API, for clients on this machine,
+// or "" when the server is not listening
+func (s *Server) URL() string {
+	if s.listener == nil {
+		return ""
+	}
+	tcp, ok := s.listener.Addr().(*net.TCPAddr)
+	if !ok {
+		return ""
+	}
+	host := "localhost"
+	if !tcp.IP.IsUnspecified() && !tcp.IP.IsLoopback() {
+		host = tcp.IP.String()
+	}
+	return "http://" + net.JoinHostPort(host, strconv.Itoa(tcp.Port))
+}
+
+// ListenAndServe listens as Listen does and serves until the server fails
+// or is shut down. After a shutdown it waits for Shutdown to finish and
+// returns nil.
+func (s *Server) ListenAndServe() error {
+	if err := s.Listen(); err != nil {
+		return err
+	}
+	slog.Info("Backend server listening", "addr", s.Addr())
+	err := s.HTTPServer.Serve(s.listener)
+	if errors.Is(err, http.ErrServerClosed) {
+		<-s.stopped
+		return nil
+	}
+	return err
+}
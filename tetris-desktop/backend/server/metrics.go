@@ -0,0 +1,236 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rateWindow is how many seconds the messages per second are averaged over
+const rateWindow = 10
+
+// metrics are the figures /metrics exposes, written in the Prometheus text
+// format by hand so that scraping needs no client library
+type metrics struct {
+	start time.Time
+
+	mu       sync.Mutex
+	sessions map[string]int       // open sessions by endpoint
+	started  map[string]uint64    // games started by mode id
+	finished map[[2]string]uint64 // games finished by mode id and result
+	hsErrors map[string]uint64    // failed highscore file operations by operation
+	messages uint64               // client messages received
+	recent   [rateWindow]uint64   // messages received in each of the last seconds
+	head     int64                // the second recent was last moved on to
+	writes   *histogram           // seconds to snapshot and send a state
+	tickLag  *histogram           // seconds game frames waited to be handled
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		start:    time.Now(),
+		sessions: map[string]int{},
+		started:  map[string]uint64{},
+		finished: map[[2]string]uint64{},
+		hsErrors: map[string]uint64{},
+		head:     time.Now().Unix(),
+		writes:   newHistogram(0.0001, 0.0005, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5),
+		tickLag:  newHistogram(0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 1),
+	}
+}
+
+// session counts a session open on endpoint until the returned function runs
+func (m *metrics) session(endpoint string) func() {
+	m.mu.Lock()
+	m.sessions[endpoint]++
+	m.mu.Unlock()
+	return func() {
+		m.mu.Lock()
+		m.sessions[endpoint]--
+		m.mu.Unlock()
+	}
+}
+
+// message counts one message from a client
+func (m *metrics) message() {
+	now := time.Now().Unix()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.roll(now)
+	m.recent[now%rateWindow]++
+	m.messages++
+}
+
+// roll empties the seconds that passed since the last message
+func (m *metrics) roll(now int64) {
+	if now-m.head >= rateWindow {
+		m.recent = [rateWindow]uint64{}
+		m.head = now
+	}
+	for m.head < now {
+		m.head++
+		m.recent[m.head%rateWindow] = 0
+	}
+}
+
+func (m *metrics) gameStarted(mode string) {
+	m.mu.Lock()
+	m.started[mode]++
+	m.mu.Unlock()
+}
+
+// gameFinished counts a game that ended with result, "completed" or "topped_out"
+func (m *metrics) gameFinished(mode, result string) {
+	m.mu.Lock()
+	m.finished[[2]string{mode, result}]++
+	m.mu.Unlock()
+}
+
+// highscoreError counts a failed read or write of the highscores file
+func (m *metrics) highscoreError(op string) {
+	m.mu.Lock()
+	m.hsErrors[op]++
+	m.mu.Unlock()
+}
+
+func (m *metrics) stateWritten(d time.Duration) {
+	m.mu.Lock()
+	m.writes.observe(d.Seconds())
+	m.mu.Unlock()
+}
+
+func (m *metrics) frameHandled(lag time.Duration) {
+	m.mu.Lock()
+	m.tickLag.observe(lag.Seconds())
+	m.mu.Unlock()
+}
+
+// histogram counts observations into buckets by upper bound
+type histogram struct {
+	bounds []float64
+	counts []uint64 // per bucket, the last one past every bound
+	sum    float64
+	count  uint64
+}
+
+func newHistogram(bounds ...float64) *histogram {
+	return &histogram{bounds: bounds, counts: make([]uint64, len(bounds)+1)}
+}
+
+func (h *histogram) observe(v float64) {
+	i, _ := slices.BinarySearch(h.bounds, v)
+	h.counts[i]++
+	h.sum += v
+	h.count++
+}
+
+func (h *histogram) write(w io.Writer, name, help string) {
+	header(w, name, help, "histogram")
+	var total uint64
+	for i, b := range h.bounds {
+		total += h.counts[i]
+		fmt.Fprintf(w, "%s_bucket{le=\"%g\"} %d\n", name, b, total)
+	}
+	fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n", name, h.count)
+	fmt.Fprintf(w, "%s_sum %g\n%s_count %d\n", name, h.sum, name, h.count)
+}
+
+func header(w io.Writer, name, help, typ string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// label quotes a label value as the text format wants it
+var label = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace
+
+// writeTo writes every metric in the Prometheus text format
+func (m *metrics) writeTo(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.roll(time.Now().Unix())
+
+	header(w, "tetris_sessions_active", "Open game sessions by endpoint.", "gauge")
+	for _, ep := range sortedKeys(m.sessions) {
+		fmt.Fprintf(w, "tetris_sessions_active{endpoint=\"%s\"} %d\n", label(ep), m.sessions[ep])
+	}
+
+	header(w, "tetris_messages_received_total", "Messages received from clients.", "counter")
+	fmt.Fprintf(w, "tetris_messages_received_total %d\n", m.messages)
+	var recent uint64
+	for _, n := range m.recent {
+		recent += n
+	}
+	header(w, "tetris_messages_per_second", fmt.Sprintf("Messages received per second over the last %d seconds.", rateWindow), "gauge")
+	fmt.Fprintf(w, "tetris_messages_per_second %g\n", float64(recent)/rateWindow)
+
+	m.writes.write(w, "tetris_state_write_seconds", "Time to snapshot a game and send the state to its client.")
+	m.tickLag.write(w, "tetris_frame_lag_seconds", "Time game frames waited before the game loop handled them.")
+
+	header(w, "tetris_games_started_total", "Games started by players, by mode.", "counter")
+	for _, mode := range sortedKeys(m.started) {
+		fmt.Fprintf(w, "tetris_games_started_total{mode=\"%s\"} %d\n", label(mode), m.started[mode])
+	}
+	header(w, "tetris_games_finished_total", "Games finished by players, by mode and result.", "counter")
+	keys := make([][2]string, 0, len(m.finished))
+	for k := range m.finished {
+		keys = append(keys, k)
+	}
+	slices.SortFunc(keys, func(a, b [2]string) int { return strings.Compare(a[0]+"\x00"+a[1], b[0]+"\x00"+b[1]) })
+	for _, k := range keys {
+		fmt.Fprintf(w, "tetris_games_finished_total{mode=\"%s\",result=\"%s\"} %d\n", label(k[0]), label(k[1]), m.finished[k])
+	}
+
+	header(w, "tetris_highscore_store_errors_total", "Failed reads and writes of the highscores file.", "counter")
+	for _, op := range []string{"load", "save"} {
+		fmt.Fprintf(w, "tetris_highscore_store_errors_total{op=\"%s\"} %d\n", op, m.hsErrors[op])
+	}
+
+	header(w, "go_goroutines", "Number of goroutines that currently exist.", "gauge")
+	fmt.Fprintf(w, "go_goroutines %d\n", runtime.NumGoroutine())
+	header(w, "process_start_time_seconds", "Start time of the process since unix epoch in seconds.", "gauge")
+	fmt.Fprintf(w, "process_start_time_seconds %d\n", m.start.Unix())
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// MetricsHandler serves the server's metrics in the Prometheus text format
+func (s *Server) MetricsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	s.metrics.writeTo(w)
+}
+
+// HealthHandler answers 200 while the server is up and 503 once it is
+// shutting down, for load balancers and uptime checks
+func (s *Server) HealthHandler(w http.ResponseWriter, r *http.Request) {
+	status, code := "ok", http.StatusOK
+	select {
+	case <-s.stop:
+		status, code = "shutting down", http.StatusServiceUnavailable
+	default:
+	}
+	s.metrics.mu.Lock()
+	sessions := 0
+	for _, n := range s.metrics.sessions {
+		sessions += n
+	}
+	s.metrics.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":   status,
+		"uptime":   int64(time.Since(s.metrics.start).Seconds()),
+		"sessions": sessions,
+	})
+}
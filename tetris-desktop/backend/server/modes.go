@@ -0,0 +1,184 @@
+package server
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"tetris-desktop/backend/model"
+	"time"
+)
+
+// built-in modes, used when no modes file is present
+//
+//go:embed modes.json
+var defaultModes []byte
+
+// ModeSet is the content of a modes file
+type ModeSet struct {
+	Default string           `json:"default"` // id of the mode used when a client names none
+	Modes   []model.GameMode `json:"modes"`   // in menu order
+}
+
+// ParseModes decodes and validates a modes file
+func ParseModes(data []byte) (*ModeSet, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var set ModeSet
+	if err := dec.Decode(&set); err != nil {
+		return nil, err
+	}
+	if err := set.validate(); err != nil {
+		return nil, err
+	}
+	return &set, nil
+}
+
+// LoadModes reads and validates a modes file
+func LoadModes(path string) (*ModeSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	set, err := ParseModes(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return set, nil
+}
+
+// validate checks every mode and the set as a whole
+func (set *ModeSet) validate() error {
+	var errs []error
+	if len(set.Modes) == 0 {
+		errs = append(errs, errors.New("no modes defined"))
+	}
+	seen := map[string]bool{}
+	for i, m := range set.Modes {
+		if err := m.Validate(); err != nil {
+			// one line per problem, each naming its mode
+			problems := []error{err}
+			if joined, ok := err.(interface{ Unwrap() []error }); ok {
+				problems = joined.Unwrap()
+			}
+			for _, e := range problems {
+				errs = append(errs, fmt.Errorf("mode %d (%q): %w", i, m.ID, e))
+			}
+		}
+		if seen[m.ID] {
+			errs = append(errs, fmt.Errorf("mode %d: duplicate id %q", i, m.ID))
+		}
+		seen[m.ID] = true
+	}
+	if set.Default != "" && !seen[set.Default] {
+		errs = append(errs, fmt.Errorf("default mode %q is not defined", set.Default))
+	}
+	return errors.Join(errs...)
+}
+
+// find returns the mode with the given id
+func (set *ModeSet) find(id string) (model.GameMode, bool) {
+	for _, m := range set.Modes {
+		if m.ID == id {
+			return m, true
+		}
+	}
+	return model.GameMode{}, false
+}
+
+// fallback returns the default mode
+func (set *ModeSet) fallback() model.GameMode {
+	if m, ok := set.find(set.Default); ok {
+		return m
+	}
+	return set.Modes[0]
+}
+
+// mustParseModes parses the built-in modes, which are known to be valid
+func mustParseModes(data []byte) *ModeSet {
+	set, err := ParseModes(data)
+	if err != nil {
+		panic("built-in modes: " + err.Error())
+	}
+	return set
+}
+
+// Modes returns the modes currently offered
+func (s *Server) Modes() *ModeSet {
+	s.modesMu.RLock()
+	defer s.modesMu.RUnlock()
+	return s.modes
+}
+
+// SetModes replaces the modes offered to new games
+func (s *Server) SetModes(set *ModeSet) {
+	s.modesMu.Lock()
+	s.modes = set
+	s.modesMu.Unlock()
+}
+
+// RankedByTime reports whether a mode's leaderboard is ordered by fastest time
+func (s *Server) RankedByTime(id string) bool {
+	m, ok := s.Modes().find(id)
+	return ok && m.Ranking == model.RankByTime
+}
+
+// LoadModesFile loads modes from path, keeping the built-in modes if the file
+// does not exist. Any other problem with the file is returned.
+func (s *Server) LoadModesFile(path string) error {
+	set, err := LoadModes(path)
+	if errors.Is(err, os.ErrNotExist) {
+		slog.Info("No modes file, using built-in modes", "path", path)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	s.SetModes(set)
+	slog.Info("Loaded modes", "count", len(set.Modes), "path", path)
+	return nil
+}
+
+// WatchModesFile reloads path whenever its modification time changes, until
+// stop is closed. An invalid file is logged and the current modes are kept.
+func (s *Server) WatchModesFile(path string, interval time.Duration, stop <-chan struct{}) {
+	var last time.Time
+	if fi, err := os.Stat(path); err == nil {
+		last = fi.ModTime()
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+			fi, err := os.Stat(path)
+			if err != nil || fi.ModTime().Equal(last) {
+				continue
+			}
+			last = fi.ModTime()
+			set, err := LoadModes(path)
+			if err != nil {
+				slog.Warn("Modes not reloaded", "path", path, "err", err)
+				continue
+			}
+			s.SetModes(set)
+			slog.Info("Reloaded modes", "count", len(set.Modes), "path", path)
+		}
+	}
+}
+
+// ModesHandler lists the modes on offer
+func (s *Server) ModesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, newAPIError("method_not_allowed", "method not allowed"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.Modes())
+}
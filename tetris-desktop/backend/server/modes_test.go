@@ -0,0 +1,64 @@
+package server
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseModes(t *testing.T) {
+	const mode = `{"id":"a","name":"A","fallSpeed":1,"scoreMultiplier":1,"ranking":"score"}`
+	tests := []struct {
+		name string
+		data string
+		errs []string // each must appear in the error; none means valid
+	}{
+		{"built-in modes", string(defaultModes), nil},
+		{"one mode", `{"modes":[` + mode + `]}`, nil},
+		{"no modes", `{"modes":[]}`, []string{"no modes defined"}},
+		{"unknown field", `{"modes":[` + mode + `],"extra":1}`, []string{`unknown field "extra"`}},
+		{"duplicate id", `{"modes":[` + mode + `,` + mode + `]}`, []string{`duplicate id "a"`}},
+		{"missing default", `{"default":"b","modes":[` + mode + `]}`, []string{`default mode "b" is not defined`}},
+		{
+			"every problem of a mode",
+			`{"modes":[{"id":"x","fallSpeed":0,"scoreMultiplier":1,"ranking":"time","lineClearDelay":-1}]}`,
+			[]string{
+				`mode 0 ("x"): name is required`,
+				`mode 0 ("x"): fallSpeed must be at least 1`,
+				`mode 0 ("x"): lineClearDelay and spawnDelay must not be negative`,
+				`mode 0 ("x"): ranking "time" needs a lines or garbage goal`,
+			},
+		},
+		{
+			"garbage goal without garbage",
+			`{"modes":[{"id":"d","name":"D","fallSpeed":1,"scoreMultiplier":1,"ranking":"time","goal":{"garbage":true}}]}`,
+			[]string{"garbage goal needs garbage rows"},
+		},
+		{
+			"pieces wider than the board",
+			`{"modes":[{"id":"p","name":"P","fallSpeed":1,"scoreMultiplier":1,"ranking":"score","pieceSet":"pentomino","width":4}]}`,
+			[]string{`width and buffer must fit the 5-cell pieces of set "pentomino"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set, err := ParseModes([]byte(tt.data))
+			if len(tt.errs) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if len(set.Modes) == 0 {
+					t.Fatal("no modes parsed")
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("no error")
+			}
+			for _, want := range tt.errs {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not mention %q", err, want)
+				}
+			}
+		})
+	}
+}
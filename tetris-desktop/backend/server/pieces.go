@@ -0,0 +1,25 @@
+package server
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"tetris-desktop/backend/model"
+)
+
+// LoadPieceSetsFile adds the piece sets defined in path to the built-in ones.
+// A missing file is not an error. Load piece sets before modes, which refer
+// to them by name.
+func (s *Server) LoadPieceSetsFile(path string) error {
+	sets, err := model.LoadPieceSets(path)
+	if errors.Is(err, os.ErrNotExist) {
+		slog.Info("No piece set file, using built-in piece sets", "path", path)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	model.AddPieceSets(sets)
+	slog.Info("Loaded piece sets", "count", len(sets), "path", path)
+	return nil
+}
@@ -0,0 +1,349 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"tetris-desktop/backend/model"
+)
+
+// folders of the data directory holding finished games and saved ones
+const (
+	replaysDir = "replays"
+	savesDir   = "saves"
+	// maxReplays is how many finished games are kept, newest first
+	maxReplays = 50
+)
+
+// ErrNotFound is returned for a replay or save that does not exist
+var ErrNotFound = errors.New("not found")
+
+var validID = regexp.MustCompile(`^[0-9A-Za-z-]+$`)
+
+// ReplayEvent is one thing that happened to a game: a player input, a held
+// key repeating, a gravity tick, a delay ending or the time limit running out
+type ReplayEvent struct {
+	T    int64  `json:"t"` // play time in milliseconds
+	Type string `json:"type"`
+	Dir  string `json:"dir,omitempty"`
+}
+
+// Replay records a game as its mode, seed and events, which is enough to
+// play it again exactly. Saved games are replays of unfinished games that
+// are played back to where they were left and then continued.
+type Replay struct {
+	ID      string         `json:"id"`
+	Name    string         `json:"name,omitempty"`
+	When    time.Time      `json:"when"`
+	Mode    model.GameMode `json:"mode"`
+	Seed    int64          `json:"seed"`
+	Score   int            `json:"score"`
+	Lines   int            `json:"lines"`
+	Pieces  int            `json:"pieces"`
+	Elapsed int64          `json:"elapsed"` // play time in milliseconds
+	Ended   bool           `json:"ended"`
+	// the game reached its mode's goal rather than topping out
+	Completed bool `json:"completed,omitempty"`
+	// Handling the player's held keys repeated with; the moves it made are
+	// among the events
+	Handling *Handling     `json:"handling,omitempty"`
+	Stats    *model.Stats  `json:"stats,omitempty"` // once the game has ended
+	Events   []ReplayEvent `json:"events,omitempty"`
+}
+
+// newReplay starts recording g
+func newReplay(g *model.Game) *Replay {
+	s := g.Snapshot()
+	return &Replay{
+		ID:   newID(s.Seed),
+		When: time.Now().UTC(),
+		Mode: s.Mode,
+		Seed: s.Seed,
+	}
+}
+
+func newID(seed int64) string {
+	now := time.Now().UTC()
+	return fmt.Sprintf("%s%03d-%04x", now.Format("20060102-150405"), now.Nanosecond()/1e6, uint16(seed))
+}
+
+// record performs ev on g as happening now, keeping it if it changed
+// anything; events that did nothing would play back as nothing too
+func (r *Replay) record(g *model.Game, ev ReplayEvent) bool {
+	ev.T = g.PlayTime().Milliseconds()
+	if !applyEvent(g, ev) {
+		return false
+	}
+	r.Events = append(r.Events, ev)
+	return true
+}
+
+// update copies the results of g so far into r
+func (r *Replay) update(g *model.Game) {
+	s := g.Snapshot()
+	r.Score, r.Lines, r.Pieces = s.Score, s.Lines, s.Pieces
+	r.Elapsed = s.Elapsed
+	r.Ended = s.GameOver || s.Completed
+	r.Completed = s.Completed
+	r.Stats = s.Summary
+}
+
+// Play replays every event on a new game and returns it where the
+// recording left off. The game runs on a frame clock, so play time only
+// moves to the times recorded.
+func (r *Replay) Play() *model.Game {
+	g := model.NewSeededGame(r.Mode, r.Seed)
+	g.UseFrameClock()
+	for _, ev := range r.Events {
+		g.SetPlayTime(time.Duration(ev.T) * time.Millisecond)
+		applyEvent(g, ev)
+	}
+	g.SetPlayTime(time.Duration(r.Elapsed) * time.Millisecond)
+	return g
+}
+
+// summary returns r without its events, for listings
+func (r Replay) summary() Replay {
+	r.Events = nil
+	return r
+}
+
+// applyEvent performs one live or recorded event on g, reporting whether
+// it changed anything
+func applyEvent(g *model.Game, ev ReplayEvent) bool {
+	switch ev.Type {
+	case "move":
+		switch ev.Dir {
+		case "left":
+			return g.MoveLeft()
+		case "right":
+			return g.MoveRight()
+		case "down":
+			return g.MoveDown()
+		}
+	case "repeat":
+		return g.AutoRepeat(ev.Dir)
+	case "rotate":
+		return g.Rotate()
+	case "drop":
+		return g.Drop()
+	case "hold":
+		return g.Hold()
+	case "pause/resume":
+		return g.TogglePause()
+	case "tick":
+		g.Step()
+		return true
+	case "update":
+		return g.Update()
+	case "expire":
+		return g.Expire()
+	}
+	return false
+}
+
+// isInput reports whether a client message is a game input to record
+func isInput(msgType string) bool {
+	switch msgType {
+	case "move", "rotate", "drop", "hold", "pause/resume":
+		return true
+	}
+	return false
+}
+
+// docPath returns where a replay or save with id is kept
+func (s *Server) docPath(dir, id string) (string, error) {
+	if !validID.MatchString(id) {
+		return "", ErrNotFound
+	}
+	return filepath.Join(s.dataDir, dir, id+".json"), nil
+}
+
+func (s *Server) writeReplay(dir string, r *Replay) error {
+	path, err := s.docPath(dir, r.ID)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (s *Server) readReplay(dir, id string) (*Replay, error) {
+	path, err := s.docPath(dir, id)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var r Replay
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &r, nil
+}
+
+// listReplays returns the summaries in dir, newest first
+func (s *Server) listReplays(dir string) ([]Replay, error) {
+	entries, err := os.ReadDir(filepath.Join(s.dataDir, dir))
+	if errors.Is(err, os.ErrNotExist) {
+		return []Replay{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	out := []Replay{}
+	for _, e := range entries {
+		id, ok := strings.CutSuffix(e.Name(), ".json")
+		if !ok || e.IsDir() {
+			continue
+		}
+		r, err := s.readReplay(dir, id)
+		if err != nil {
+			slog.Warn("Skipping unreadable file", "dir", dir, "id", id, "err", err)
+			continue
+		}
+		out = append(out, r.summary())
+	}
+	slices.SortFunc(out, func(a, b Replay) int { return b.When.Compare(a.When) })
+	return out, nil
+}
+
+func (s *Server) deleteReplay(dir, id string) error {
+	path, err := s.docPath(dir, id)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// finishReplay keeps the recording of a game that has just ended and drops
+// the oldest replays beyond maxReplays
+func (s *Server) finishReplay(r *Replay) error {
+	if s.dataDir == "" {
+		return nil
+	}
+	if err := s.writeReplay(replaysDir, r); err != nil {
+		return err
+	}
+	all, err := s.listReplays(replaysDir)
+	if err != nil {
+		return err
+	}
+	for _, old := range all[min(len(all), maxReplays):] {
+		s.deleteReplay(replaysDir, old.ID)
+	}
+	return nil
+}
+
+// saveGame keeps the recording of an unfinished game under name so it can
+// be resumed later
+func (s *Server) saveGame(g *model.Game, r *Replay, name string) (*Replay, error) {
+	if s.dataDir == "" {
+		return nil, errors.New("no data directory")
+	}
+	saved := *r
+	saved.Events = slices.Clone(r.Events)
+	saved.update(g)
+	saved.ID = newID(r.Seed)
+	saved.When = time.Now().UTC()
+	saved.Name = strings.TrimSpace(name)
+	if len(saved.Name) > 40 {
+		saved.Name = saved.Name[:40]
+	}
+	return &saved, s.writeReplay(savesDir, &saved)
+}
+
+// Replays lists the recorded finished games, newest first, without their events
+func (s *Server) Replays() ([]Replay, error) {
+	return s.listReplays(replaysDir)
+}
+
+// Replay returns one recorded game with its events
+func (s *Server) Replay(id string) (*Replay, error) {
+	return s.readReplay(replaysDir, id)
+}
+
+// DeleteReplay removes a recorded game
+func (s *Server) DeleteReplay(id string) error {
+	return s.deleteReplay(replaysDir, id)
+}
+
+// Saves lists the saved games, newest first, without their events
+func (s *Server) Saves() ([]Replay, error) {
+	return s.listReplays(savesDir)
+}
+
+// DeleteSave removes a saved game
+func (s *Server) DeleteSave(id string) error {
+	return s.deleteReplay(savesDir, id)
+}
+
+// ReplaysHandler lists replays, or returns one with ?id=, and deletes one
+// with DELETE ?id=
+func (s *Server) ReplaysHandler(w http.ResponseWriter, r *http.Request) {
+	s.serveReplays(w, r, s.Replays, s.Replay, s.DeleteReplay)
+}
+
+// SavesHandler lists saved games and deletes one with DELETE ?id=. Saved
+// games are resumed by opening /ws with ?save=id.
+func (s *Server) SavesHandler(w http.ResponseWriter, r *http.Request) {
+	s.serveReplays(w, r, s.Saves, nil, s.DeleteSave)
+}
+
+func (s *Server) serveReplays(w http.ResponseWriter, r *http.Request,
+	list func() ([]Replay, error), get func(string) (*Replay, error), del func(string) error) {
+	id := r.URL.Query().Get("id")
+	var out any
+	var err error
+	switch {
+	case r.Method == http.MethodGet && id == "":
+		out, err = list()
+	case r.Method == http.MethodGet && get != nil:
+		out, err = get(id)
+	case r.Method == http.MethodDelete:
+		err = del(id)
+		out = map[string]any{"ok": true}
+	default:
+		writeError(w, http.StatusMethodNotAllowed, newAPIError("method_not_allowed", "method not allowed"))
+		return
+	}
+	if errors.Is(err, ErrNotFound) {
+		writeError(w, http.StatusNotFound, newAPIError("not_found", "not found"))
+		return
+	}
+	if err != nil {
+		slog.Error("Replays not read", "path", r.URL.Path, "err", err)
+		writeError(w, http.StatusInternalServerError, newAPIError("internal", "cannot read replays"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(out)
+}
@@ -0,0 +1,130 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"tetris-desktop/backend/model"
+)
+
+// playRecorded plays a game on the frame clock like a session does, with
+// inputs drawn from seed, recording everything into a replay
+func playRecorded(mode model.GameMode, seed int64, frames int) (*model.Game, *Replay) {
+	const frame = time.Second / 60
+	baseSpeed := New().BaseSpeed
+	g := model.NewSeededGame(mode, seed)
+	g.UseFrameClock()
+	rec := newReplay(g)
+	inputs := []ReplayEvent{
+		{Type: "move", Dir: "left"},
+		{Type: "move", Dir: "right"},
+		{Type: "move", Dir: "down"},
+		{Type: "repeat", Dir: "left"},
+		{Type: "repeat", Dir: "right"},
+		{Type: "rotate"},
+		{Type: "hold"},
+		{Type: "drop"},
+		{Type: "pause/resume"},
+	}
+	rnd := rand.New(rand.NewSource(seed))
+	nextFall := g.FallInterval(baseSpeed)
+	for range frames {
+		if !g.Running() {
+			break
+		}
+		g.Advance(frame)
+		rec.record(g, ReplayEvent{Type: "update"})
+		if g.TimeUp() {
+			rec.record(g, ReplayEvent{Type: "expire"})
+		}
+		if rnd.Intn(4) == 0 {
+			ev := inputs[rnd.Intn(len(inputs))]
+			if ev.Type == "pause/resume" && rnd.Intn(10) != 0 {
+				ev = ReplayEvent{Type: "drop"}
+			}
+			rec.record(g, ev)
+		}
+		for g.Running() && g.PlayTime() >= nextFall {
+			rec.record(g, ReplayEvent{Type: "tick"})
+			nextFall += g.FallInterval(baseSpeed)
+		}
+	}
+	rec.update(g)
+	return g, rec
+}
+
+func TestReplayRoundTrip(t *testing.T) {
+	modes := mustParseModes(defaultModes)
+	tests := []struct {
+		name   string
+		mode   string
+		seed   int64
+		frames int
+		speed  []int64 // gravity in milliseconds per row, when set
+	}{
+		{"beginner", "beginner", 1, 3600, nil},
+		{"delays", "classic-timing", 2, 3600, nil},
+		{"sprint", "sprint", 3, 3600, nil},
+		{"garbage", "dig", 4, 3600, nil},
+		{"lock delay and hold", "standard", 5, 3600, nil},
+		{"lock delay at top speed", "standard", 9, 3600, []int64{17}}, // grounded between frames
+		{"finesse retries", "finesse", 6, 3600, nil},
+		{"pentominoes", "pentomino", 7, 3600, nil},
+		{"unfinished", "marathon", 8, 300, nil}, // as saved games are
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mode, ok := modes.find(tt.mode)
+			if !ok {
+				t.Fatalf("no mode %q", tt.mode)
+			}
+			if tt.speed != nil {
+				mode.Gravity = tt.speed
+			}
+			g, rec := playRecorded(mode, tt.seed, tt.frames)
+			if rec.Pieces == 0 {
+				t.Fatal("no pieces placed")
+			}
+
+			// as written to and read back from the replays folder
+			data, err := json.Marshal(rec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var loaded Replay
+			if err := json.Unmarshal(data, &loaded); err != nil {
+				t.Fatal(err)
+			}
+
+			want, wantPPS := snapshot(g)
+			got, gotPPS := snapshot(loaded.Play())
+			if !bytes.Equal(got, want) {
+				t.Errorf("replay of %d events differs\n got %s\nwant %s", len(rec.Events), got, want)
+			}
+			// the play times the rates come from may differ by under a millisecond
+			if gotPPS != wantPPS {
+				n := float64(rec.Pieces)
+				if math.Abs(n/gotPPS-n/wantPPS) >= 0.001 {
+					t.Errorf("replay plays %g pieces per second, want %g", gotPPS, wantPPS)
+				}
+			}
+		})
+	}
+}
+
+// snapshot returns the state of g to compare and its pieces per second,
+// which is left out of the state: replays keep time in whole milliseconds,
+// so the rate is only as exact as that
+func snapshot(g *model.Game) ([]byte, float64) {
+	s := g.Snapshot()
+	var pps float64
+	if s.Summary != nil {
+		pps, s.Summary.PPS = s.Summary.PPS, 0
+	}
+	data, _ := json.Marshal(&s)
+	return data, pps
+}
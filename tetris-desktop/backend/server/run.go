@@ -0,0 +1,79 @@
+package server
+
+import (
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultAddr is where the server listens unless told otherwise
+const DefaultAddr = ":8081"
+
+// Config says where the server keeps its files, what it serves and where
+type Config struct {
+	Addr    string     // listen address, host:port; DefaultAddr when empty
+	Ports   *PortRange // ports to try when Addr is taken; any free port when nil
+	DataDir string     // holds highscores, settings, replays, saves and the optional modes.json and pieces.json
+	Assets  fs.FS      // frontend served at /, nil to serve the API only
+	Token   string     // access token API clients must present; "" lets anyone in
+	Origins []string   // browser origins besides the backend's own allowed to use the API
+}
+
+// DataDir returns the directory of the running executable, where the
+// desktop app keeps its files, or the working directory if it is unknown
+func DataDir() string {
+	exe, err := os.Executable()
+	if err != nil {
+		slog.Warn("Executable path unknown, using the working directory", "err", err)
+		return "."
+	}
+	return filepath.Dir(exe)
+}
+
+// Setup loads the data files, watches the modes file for changes and
+// registers every handler, ready for Listen and ListenAndServe
+func (s *Server) Setup(cfg Config) error {
+	s.dataDir = cfg.DataDir
+	s.token = cfg.Token
+	s.origins = cfg.Origins
+	piecesFile := filepath.Join(cfg.DataDir, "pieces.json")
+	modesFile := filepath.Join(cfg.DataDir, "modes.json")
+	if err := s.LoadPieceSetsFile(piecesFile); err != nil {
+		return err
+	}
+	if err := s.LoadModesFile(modesFile); err != nil {
+		return err
+	}
+	go s.WatchModesFile(modesFile, 2*time.Second, s.stop)
+	s.LoadHighscores(filepath.Join(cfg.DataDir, "highscores.json"))
+	if err := s.loadSettings(); err != nil {
+		slog.Error("Settings not loaded", "err", err)
+	}
+	if err := s.loadStats(); err != nil {
+		slog.Error("Statistics not loaded", "err", err)
+	}
+
+	s.RegisterHandlers()
+	if cfg.Assets != nil {
+		s.mux.Handle("/", s.assetsHandler(cfg.Assets))
+		s.mux.HandleFunc("/config.js", s.ConfigScript)
+	}
+
+	addr := cfg.Addr
+	if addr == "" {
+		addr = DefaultAddr
+	}
+	s.HTTPServer = &http.Server{Addr: addr, Handler: s.mux, ReadHeaderTimeout: readHeader}
+	s.ports = cfg.Ports
+	return nil
+}
+
+// ConfigScript tells pages served by the backend itself to talk to the host
+// they were loaded from rather than the desktop app's fixed address
+func (s *Server) ConfigScript(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/javascript")
+	w.Write([]byte("window.tetrisBackend = location.origin;\n"))
+}
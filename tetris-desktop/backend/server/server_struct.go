@@ -1,8 +1,10 @@
 package server
 
 import (
+	"io"
+	"net"
 	"net/http"
-	"tetris-desktop/backend/model"
+	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -10,37 +12,63 @@ import (
 
 // Server holds config and shared resources for handlers
 type Server struct {
-	Upgrader     websocket.Upgrader
-	BeginnerMode model.GameMode
-	ClassicMode  model.GameMode
-	BaseSpeed    time.Duration
+	Upgrader  websocket.Upgrader
+	BaseSpeed time.Duration
+	// FrameRate is how many frames a second games are simulated in and
+	// SendRate how many times a second a changed state is sent at most
+	FrameRate int
+	SendRate  int
 	// exported so main or tests can read/adjust if needed
 	HTTPServer *http.Server
+	// OnQuit is called when the frontend asks to quit, and should end up
+	// calling Shutdown; nil ignores the request
+	OnQuit func()
+
+	mux       *http.ServeMux
+	ports     *PortRange
+	listener  net.Listener // open once Listen succeeded
+	token     string       // required from API clients unless empty
+	origins   []string     // browser origins allowed besides the backend's own
+	apiLimits clientLimits
+	metrics   *metrics
+	dataDir   string // where highscores, settings, replays and saves are kept
+	modesMu   sync.RWMutex
+	modes     *ModeSet
+
+	settingsMu sync.Mutex
+	settings   Settings
+
+	statsMu  sync.Mutex
+	lifetime LifetimeStats
+
+	hsMu       sync.Mutex
+	hsFile     string
+	highscores []Highscore
+
+	sessionsMu   sync.Mutex
+	sessions     map[io.Closer]struct{}
+	closing      bool
+	running      sync.WaitGroup // sessions not yet returned
+	stop         chan struct{}  // closed when shutdown starts
+	stopped      chan struct{}  // closed when shutdown has finished
+	shutdownOnce sync.Once
+	shutdownErr  error
 }
 
-// New creates a configured Server instance
+// New creates a configured Server instance with the built-in modes
 func New() *Server {
 	s := &Server{
-		Upgrader: websocket.Upgrader{
-			CheckOrigin: func(r *http.Request) bool { return true },
-		},
-		BeginnerMode: model.GameMode{
-			Name:            "Beginner",
-			GhostPiece:      true,
-			NextPreview:     true,
-			CanPause:        true,
-			FallSpeed:       1,
-			ScoreMultiplier: 1.0,
-		},
-		ClassicMode: model.GameMode{
-			Name:            "Classic",
-			GhostPiece:      false,
-			NextPreview:     false,
-			CanPause:        false,
-			FallSpeed:       2,
-			ScoreMultiplier: 2.0,
-		},
-		BaseSpeed: 600 * time.Millisecond,
+		BaseSpeed:  600 * time.Millisecond,
+		FrameRate:  60,
+		SendRate:   60,
+		mux:        http.NewServeMux(),
+		modes:      mustParseModes(defaultModes),
+		highscores: []Highscore{},
+		sessions:   map[io.Closer]struct{}{},
+		stop:       make(chan struct{}),
+		stopped:    make(chan struct{}),
+		metrics:    newMetrics(),
 	}
+	s.Upgrader.CheckOrigin = s.checkOrigin
 	return s
 }
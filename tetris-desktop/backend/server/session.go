@@ -1,121 +1,452 @@
 package server
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
-	"log"
+	"io"
+	"log/slog"
 	"net/http"
+	"strconv"
 	"sync"
 	"tetris-desktop/backend/model"
 	"time"
+
+	"github.com/gorilla/websocket"
 )
 
 type wsMessage struct {
 	Type string `json:"type"`
 	Dir  string `json:"dir,omitempty"`
+	Mode string `json:"mode,omitempty"`
+	Name string `json:"name,omitempty"` // of a saved game
+	modeOptions
+
+	err error // why the message could not be read
+}
+
+// check returns what is wrong with a client message, or nil if it can be
+// handled
+func (m *wsMessage) check() *apiError {
+	if m.err != nil {
+		return newAPIError("bad_request", "invalid message: "+m.err.Error())
+	}
+	switch m.Type {
+	case "restart", "save", "rotate", "drop", "hold", "pause/resume":
+		return nil
+	case "move", "press", "release":
+		if m.Dir == "left" || m.Dir == "right" || m.Dir == "down" {
+			return nil
+		}
+		return newAPIError("bad_request", m.Type+" needs dir left, right or down")
+	}
+	return newAPIError("bad_request", "unknown message type "+strconv.Quote(m.Type))
+}
+
+// maxCatchUp is how far a session's frames may fall behind the wall clock;
+// time lost beyond it, when the process was stalled, is not played
+const maxCatchUp = 250 * time.Millisecond
+
+// sessionState is a snapshot as a session sends it. Once the game is over
+// it names the game's replay, which a highscore submission refers to.
+type sessionState struct {
+	*model.GameState
+	Replay string `json:"replay,omitempty"`
+}
+
+// colorState is a session state with the board as plain colour values, for
+// clients that connect with ?cells=colors
+type colorState struct {
+	sessionState
+	Board [][]int `json:"board"`
+}
+
+// SessionConn carries the messages of one game session, over a websocket
+// or through the desktop app's bindings. Sessions that are also io.Closers
+// are closed when the server shuts down.
+type SessionConn interface {
+	ReadJSON(v any) error
+	WriteJSON(v any) error
+}
+
+// SessionOptions choose how a game session starts
+type SessionOptions struct {
+	Mode   model.GameMode // also used by restarts that name no mode
+	Save   string         // id of a saved game to resume instead
+	Colors bool           // send the board as plain colour values
+	Log    *slog.Logger   // for the session's lines; nil tags them with a new session id
+}
+
+// newSessionID returns a short random id that ties together the log lines
+// of one session
+func newSessionID() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// wsSession is a websocket game session; closing it says goodbye first
+type wsSession struct {
+	*websocket.Conn
+}
+
+func (c wsSession) Close() error {
+	closeSession(c.Conn)
+	return nil
 }
 
 // WSHandler handles a websocket connection and runs the game loop
 func (s *Server) WSHandler(w http.ResponseWriter, r *http.Request) {
-	conn, err := s.Upgrader.Upgrade(w, r, nil)
+	log := slog.With("session", newSessionID(), "remote", r.RemoteAddr)
+	conn, stop, err := s.upgrade(w, r)
 	if err != nil {
-		log.Println("ws upgrade:", err)
+		log.Warn("Websocket upgrade failed", "err", err)
 		return
 	}
 	defer conn.Close()
+	defer stop()
+
+	q := r.URL.Query()
+	s.RunSession(wsSession{conn}, SessionOptions{
+		Mode:   s.getModeFromSessionOrDefault(r),
+		Save:   q.Get("save"),
+		Colors: q.Get("cells") == "colors",
+		Log:    log,
+	})
+}
+
+// RunSession plays games for one client until its connection fails: it
+// applies the client's inputs, repeats held keys with the profile's
+// handling, runs gravity and time limits in fixed frames, sends the latest
+// state after changes and records each game as a replay. Messages beyond
+// the input rate limit or that make no sense are dropped with an error
+// message to the client.
+func (s *Server) RunSession(conn SessionConn, opts SessionOptions) {
+	if c, ok := conn.(io.Closer); ok {
+		defer s.track(c)()
+	}
+	endpoint := "desktop"
+	if _, ok := conn.(wsSession); ok {
+		endpoint = "ws"
+	}
+	defer s.metrics.session(endpoint)()
+	log := opts.Log
+	if log == nil {
+		log = slog.With("session", newSessionID())
+	}
+	log.Info("Session opened", "endpoint", endpoint)
+	defer log.Info("Session closed")
 
-	g := model.NewGame(s.getModeFromSessionOrDefault(r))
-	log.Println("Starting game with mode:", g.Mode.Name)
+	var g *model.Game
+	var rec *Replay
+	begin := func(mode model.GameMode) {
+		g = model.NewGame(mode)
+		rec = newReplay(g)
+		s.metrics.gameStarted(mode.ID)
+		log.Info("Starting game", "mode", mode.ID, "seed", rec.Seed)
+	}
+	if opts.Save != "" {
+		saved, err := s.readReplay(savesDir, opts.Save)
+		if err == nil {
+			g = saved.Play()
+			rec = saved
+			rec.ID = newID(rec.Seed)
+			rec.Name = ""
+			s.metrics.gameStarted(saved.Mode.ID)
+			log.Info("Resuming saved game", "save", opts.Save, "mode", saved.Mode.ID)
+		} else {
+			log.Warn("Cannot resume saved game", "save", opts.Save, "err", err)
+		}
+	}
+	if g == nil {
+		begin(opts.Mode)
+	}
+	// a game cut short by a shutdown is kept as a save to resume later
+	defer func() {
+		if !s.stopping() || rec.Ended || len(rec.Events) == 0 {
+			return
+		}
+		if saved, err := s.saveGame(g, rec, "Interrupted by shutdown"); err != nil {
+			log.Error("Unfinished game not saved", "err", err)
+		} else {
+			log.Info("Saved unfinished game", "save", saved.ID)
+		}
+	}()
 
-	var ticker *time.Ticker
-	createTicker := func() {
-		if ticker != nil {
-			ticker.Stop()
+	// the game runs on the loop's frames, not the wall clock
+	frame := time.Second / time.Duration(s.FrameRate)
+	var nextFall time.Duration // play time the next gravity step is due
+	var keys *repeater         // held keys, set up once record is
+	started := func() {
+		g.UseFrameClock()
+		nextFall = g.PlayTime() + g.FallInterval(s.BaseSpeed)
+		h := s.Handling()
+		rec.Handling = &h
+		keys.reset(h, g.PlayTime())
+	}
+
+	// record performs ev and keeps the replay once the game is over
+	record := func(ev ReplayEvent) bool {
+		if rec.Ended {
+			return false
+		}
+		changed := rec.record(g, ev)
+		if changed {
+			rec.update(g)
+			if rec.Ended {
+				result := "topped_out"
+				if g.Snapshot().Completed {
+					result = "completed"
+				}
+				s.metrics.gameFinished(rec.Mode.ID, result)
+				log.Info("Game finished", "mode", rec.Mode.ID, "result", result,
+					"score", rec.Score, "lines", rec.Lines, "pieces", rec.Pieces, "elapsed", rec.Elapsed)
+				if err := s.finishReplay(rec); err != nil {
+					log.Error("Replay not saved", "err", err)
+				}
+				state := g.Snapshot()
+				if err := s.addStats(&state); err != nil {
+					log.Error("Statistics not saved", "err", err)
+				}
+			}
 		}
-		speed := s.BaseSpeed / time.Duration(g.Mode.FallSpeed)
-		ticker = time.NewTicker(speed)
+		return changed
 	}
 
-	createTicker()
-	quit := make(chan struct{})
-	var writeMu sync.Mutex
-	restartChan := make(chan model.GameMode)
+	// held keys move the piece as recorded moves, only while it can move
+	keys = newRepeater(DefaultHandling, func(dir string, repeat bool) bool {
+		ev := ReplayEvent{Type: "move", Dir: dir}
+		if repeat {
+			ev.Type = "repeat"
+		}
+		return g.Running() && record(ev)
+	})
+	started()
 
+	// step runs one frame: the clock moves on, delays that are over end, a
+	// timed mode ends when its time is up, held keys repeat and gravity steps for every fall
+	// interval that has passed
+	step := func() bool {
+		g.Advance(frame)
+		changed := record(ReplayEvent{Type: "update"})
+		if g.TimeUp() && record(ReplayEvent{Type: "expire"}) {
+			changed = true
+		}
+		if g.Running() && keys.update(g.PlayTime(), g.FallInterval(s.BaseSpeed)) {
+			changed = true
+		}
+		for g.Running() && g.PlayTime() >= nextFall {
+			record(ReplayEvent{Type: "tick"})
+			nextFall += g.FallInterval(s.BaseSpeed)
+			changed = true
+		}
+		return changed
+	}
+
+	// States are written on their own goroutine, so a slow client never
+	// holds up the game. Only the latest state waits to go out; errors for
+	// the client queue beside it.
+	states := make(chan *sessionState, 1)
+	notices := make(chan *apiError, 4)
+	writeFailed := make(chan struct{})
+	done := make(chan struct{})
+	defer close(done)
 	go func() {
+		defer close(writeFailed)
 		for {
-			var msg wsMessage
-			if err := conn.ReadJSON(&msg); err != nil {
-				close(quit)
+			var err error
+			select {
+			case state := <-states:
+				start := time.Now()
+				if opts.Colors {
+					err = conn.WriteJSON(colorState{*state, state.ColorGrid()})
+				} else {
+					err = conn.WriteJSON(state)
+				}
+				s.metrics.stateWritten(time.Since(start))
+			case notice := <-notices:
+				err = conn.WriteJSON(notice)
+			case <-done:
 				return
 			}
-
-			if msg.Type == "restart" {
-				log.Println("Restart message received")
-				var rm restartMsg
-				data, _ := json.Marshal(msg)
-				json.Unmarshal(data, &rm)
-				selectedMode := s.getModeFromSessionOrDefault(r)
-				switch rm.Mode {
-				case "classic":
-					selectedMode = s.ClassicMode
-				case "beginner":
-					selectedMode = s.BeginnerMode
-				}
-				restartChan <- selectedMode
-				continue
+			if err != nil {
+				return
 			}
+		}
+	}()
+	send := func() {
+		state := g.Snapshot()
+		msg := &sessionState{GameState: &state}
+		if rec.Ended {
+			msg.Replay = rec.ID
+		}
+		select {
+		case <-states:
+		default:
+		}
+		states <- msg
+	}
+	notify := func(err *apiError) {
+		select {
+		case notices <- err:
+		default:
+		}
+	}
+
+	limiter := newInputLimiter(inputRate, inputBurst)
 
-			updated := false
-
-			switch msg.Type {
-			case "move":
-				switch msg.Dir {
-				case "left":
-					updated = g.MoveLeft()
-				case "right":
-					updated = g.MoveRight()
-				case "down":
-					updated = g.MoveDown()
+	// inputs are read on their own goroutine and handled in the loop below,
+	// so only the loop ever touches the game
+	inputs := make(chan wsMessage)
+	quit := make(chan struct{})
+	go func() {
+		defer close(quit)
+		for {
+			var msg wsMessage
+			if err := conn.ReadJSON(&msg); err != nil {
+				if !badJSON(err) {
+					readError(log, err)
+					return
 				}
-			case "rotate":
-				updated = g.Rotate()
-			case "drop":
-				updated = g.Drop()
-			case "pause/resume":
-				updated = g.TogglePause()
+				msg = wsMessage{err: err}
 			}
-
-			if updated {
-				writeMu.Lock()
-				conn.WriteJSON(g.Snapshot())
-				writeMu.Unlock()
+			s.metrics.message()
+			select {
+			case inputs <- msg:
+			case <-done:
+				return
 			}
 		}
 	}()
 
+	// Frames are counted from the wall clock, so a late timer runs the
+	// frames it missed rather than slowing the game, up to maxCatchUp.
+	frames := time.NewTicker(frame)
+	defer frames.Stop()
+	sends := time.NewTicker(time.Second / time.Duration(s.SendRate))
+	defer sends.Stop()
+	last := time.Now()
+	var behind time.Duration
+	dirty := false
+
 	// send initial state
-	writeMu.Lock()
-	conn.WriteJSON(g.Snapshot())
-	writeMu.Unlock()
+	send()
 
 	for {
 		select {
 		case <-quit:
 			return
-		case mode := <-restartChan:
-			g = model.NewGame(mode)
-			log.Println("Starting game with mode:", g.Mode.Name)
-			createTicker()
-			writeMu.Lock()
-			conn.WriteJSON(g.Snapshot())
-			writeMu.Unlock()
-		case <-ticker.C:
-			g.Step()
-			writeMu.Lock()
-			if err := conn.WriteJSON(g.Snapshot()); err != nil {
-				writeMu.Unlock()
-				return
+		case <-writeFailed:
+			return
+		case msg := <-inputs:
+			// releases change nothing but the held keys, so they always
+			// get through; anything dropped lets go of every key, or a
+			// lost release would leave it repeating
+			if msg.Type != "release" {
+				if ok, err := limiter.check(); !ok {
+					keys.releaseAll()
+					if err != nil {
+						notify(err)
+					}
+					continue
+				}
+			}
+			if err := msg.check(); err != nil {
+				notify(err)
+				continue
+			}
+			switch {
+			case msg.Type == "restart":
+				log.Debug("Restart requested", "mode", msg.Mode)
+				mode := opts.Mode
+				if msg.Mode != "" {
+					mode = s.modeByName(msg.Mode, msg.modeOptions)
+				}
+				begin(mode)
+				started()
+				dirty = true
+			case msg.Type == "save":
+				if saved, err := s.saveGame(g, rec, msg.Name); err != nil {
+					log.Error("Game not saved", "err", err)
+				} else {
+					log.Info("Saved game", "save", saved.ID)
+				}
+			case msg.Type == "press":
+				if keys.press(msg.Dir, g.PlayTime(), g.FallInterval(s.BaseSpeed)) {
+					dirty = true
+				}
+			case msg.Type == "release":
+				keys.release(msg.Dir, g.PlayTime())
+			case isInput(msg.Type):
+				if record(ReplayEvent{Type: msg.Type, Dir: msg.Dir}) {
+					dirty = true
+				}
+			}
+		case t := <-frames.C:
+			s.metrics.frameHandled(time.Since(t))
+			now := time.Now()
+			behind = min(behind+now.Sub(last), maxCatchUp)
+			last = now
+			for ; behind >= frame; behind -= frame {
+				if step() {
+					dirty = true
+				}
+			}
+		case <-sends.C:
+			if dirty {
+				send()
+				dirty = false
 			}
-			writeMu.Unlock()
 		}
 	}
 }
+
+// ChanConn is a SessionConn driven through Go calls instead of a network
+// connection, for the desktop app's bindings
+type ChanConn struct {
+	in     chan []byte
+	closed chan struct{}
+	once   sync.Once
+	send   func(v any) error
+}
+
+// NewChanConn returns a session connection whose states go to send
+func NewChanConn(send func(v any) error) *ChanConn {
+	return &ChanConn{in: make(chan []byte), closed: make(chan struct{}), send: send}
+}
+
+// Input passes one client message to the session
+func (c *ChanConn) Input(msg []byte) error {
+	select {
+	case c.in <- msg:
+		return nil
+	case <-c.closed:
+		return io.ErrClosedPipe
+	}
+}
+
+// ReadJSON waits for the next client message
+func (c *ChanConn) ReadJSON(v any) error {
+	select {
+	case msg := <-c.in:
+		return json.Unmarshal(msg, v)
+	case <-c.closed:
+		return io.EOF
+	}
+}
+
+// WriteJSON hands a state to the client
+func (c *ChanConn) WriteJSON(v any) error {
+	select {
+	case <-c.closed:
+		return io.ErrClosedPipe
+	default:
+		return c.send(v)
+	}
+}
+
+// Close ends the session
+func (c *ChanConn) Close() error {
+	c.once.Do(func() { close(c.closed) })
+	return nil
+}
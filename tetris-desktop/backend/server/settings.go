@@ -0,0 +1,92 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"maps"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// maxSettings caps how many preferences a client may store
+const maxSettings = 100
+
+// Settings are the player's preferences, by the keys the frontend stores
+// them under, such as "gameMode" or "ghostPieceEnabled"
+type Settings map[string]string
+
+// loadSettings reads settings.json from the data directory; a missing file
+// means no preferences yet
+func (s *Server) loadSettings() error {
+	data, err := os.ReadFile(filepath.Join(s.dataDir, "settings.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var set Settings
+	if err := json.Unmarshal(data, &set); err != nil {
+		return err
+	}
+	s.settingsMu.Lock()
+	s.settings = set
+	s.settingsMu.Unlock()
+	return nil
+}
+
+// Settings returns a copy of the stored preferences
+func (s *Server) Settings() Settings {
+	s.settingsMu.Lock()
+	defer s.settingsMu.Unlock()
+	out := Settings{}
+	maps.Copy(out, s.settings)
+	return out
+}
+
+// SaveSettings replaces the stored preferences and writes them to disk
+func (s *Server) SaveSettings(set Settings) error {
+	if len(set) > maxSettings {
+		return errors.New("too many settings")
+	}
+	data, err := json.MarshalIndent(set, "", "  ")
+	if err != nil {
+		return err
+	}
+	s.settingsMu.Lock()
+	defer s.settingsMu.Unlock()
+	s.settings = maps.Clone(set)
+	if s.dataDir == "" {
+		return nil
+	}
+	path := filepath.Join(s.dataDir, "settings.json")
+	if err := os.WriteFile(path+".tmp", data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// SettingsHandler serves GET /settings and POST /settings
+func (s *Server) SettingsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.Settings())
+	case http.MethodPost:
+		var set Settings
+		if !decodeBody(w, r, &set) {
+			return
+		}
+		if err := s.SaveSettings(set); err != nil {
+			slog.Error("Settings not saved", "err", err)
+			writeError(w, http.StatusBadRequest, newAPIError("bad_request", err.Error()))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"ok": true})
+	default:
+		writeError(w, http.StatusMethodNotAllowed, newAPIError("method_not_allowed", "method not allowed"))
+	}
+}
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// ShutdownTimeout is how long a shutdown may wait for requests to finish
+const ShutdownTimeout = 5 * time.Second
+
+// track registers a session so Shutdown can close it and wait for it,
+// returning the function the session calls once it is done. Sessions
+// opened during a shutdown are closed straight away.
+func (s *Server) track(conn io.Closer) func() {
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+	if s.closing {
+		conn.Close()
+		return func() {}
+	}
+	s.sessions[conn] = struct{}{}
+	s.running.Add(1)
+	return func() {
+		s.sessionsMu.Lock()
+		defer s.sessionsMu.Unlock()
+		delete(s.sessions, conn)
+		s.running.Done()
+	}
+}
+
+// stopping reports whether a shutdown has started
+func (s *Server) stopping() bool {
+	select {
+	case <-s.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// closeSession tells the client the server is going away and drops the connection
+func closeSession(conn *websocket.Conn) {
+	msg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+	conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+	conn.Close()
+}
+
+// Shutdown stops accepting connections, waits for requests in flight until
+// ctx is done, closes every game session with a close frame and waits,
+// again until ctx is done, for the sessions to write out their replays,
+// statistics and unfinished games. Then it writes the highscores out.
+// ListenAndServe returns once it has finished.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.shutdownOnce.Do(func() {
+		slog.Info("Shutting down")
+		close(s.stop)
+		if s.HTTPServer != nil {
+			s.shutdownErr = s.HTTPServer.Shutdown(ctx)
+		}
+
+		s.sessionsMu.Lock()
+		s.closing = true
+		for conn := range s.sessions {
+			conn.Close()
+		}
+		s.sessionsMu.Unlock()
+
+		idle := make(chan struct{})
+		go func() {
+			s.running.Wait()
+			close(idle)
+		}()
+		select {
+		case <-idle:
+		case <-ctx.Done():
+			slog.Warn("Sessions still running at shutdown")
+			if s.shutdownErr == nil {
+				s.shutdownErr = ctx.Err()
+			}
+		}
+
+		s.hsMu.Lock()
+		s.saveHighscores()
+		s.hsMu.Unlock()
+		close(s.stopped)
+	})
+	<-s.stopped
+	return s.shutdownErr
+}
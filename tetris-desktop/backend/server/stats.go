@@ -0,0 +1,131 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"tetris-desktop/backend/model"
+)
+
+// Totals add up the finished games of the profile, or of one mode
+type Totals struct {
+	Games    int   `json:"games"`
+	PlayTime int64 `json:"playTime"` // milliseconds
+	Pieces   int   `json:"pieces"`
+	Lines    int   `json:"lines"`
+	Score    int   `json:"score"`
+	model.Stats
+}
+
+// add counts one finished game in, working out the rates again
+func (t *Totals) add(g *model.GameState, stats model.Stats) {
+	t.Games++
+	t.PlayTime += g.Elapsed
+	t.Pieces += g.Pieces
+	t.Lines += g.Lines
+	t.Score += g.Score
+	t.Stats.Add(stats)
+	t.PPS, t.InputsPerPiece = 0, 0
+	if t.PlayTime > 0 {
+		t.PPS = float64(t.Pieces) / (float64(t.PlayTime) / 1000)
+	}
+	if t.Pieces > 0 {
+		t.InputsPerPiece = float64(t.Inputs) / float64(t.Pieces)
+	}
+}
+
+// LifetimeStats are the profile's totals over every finished game, and by mode
+type LifetimeStats struct {
+	Totals
+	Modes map[string]*Totals `json:"modes"`
+}
+
+// statsPath returns where the lifetime statistics are kept
+func (s *Server) statsPath() string {
+	return filepath.Join(s.dataDir, "stats.json")
+}
+
+// loadStats reads stats.json from the data directory; a missing file
+// means no games finished yet
+func (s *Server) loadStats() error {
+	data, err := os.ReadFile(s.statsPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var st LifetimeStats
+	if err := json.Unmarshal(data, &st); err != nil {
+		return err
+	}
+	s.statsMu.Lock()
+	s.lifetime = st
+	s.statsMu.Unlock()
+	return nil
+}
+
+// Stats returns the lifetime statistics
+func (s *Server) Stats() LifetimeStats {
+	s.statsMu.Lock()
+	defer s.statsMu.Unlock()
+	// round trip for a deep copy
+	var out LifetimeStats
+	data, _ := json.Marshal(s.lifetime)
+	json.Unmarshal(data, &out)
+	if out.Modes == nil {
+		out.Modes = map[string]*Totals{}
+	}
+	if out.PiecesByType == nil {
+		out.PiecesByType = map[string]int{}
+	}
+	if out.FinesseByType == nil {
+		out.FinesseByType = map[string]int{}
+	}
+	return out
+}
+
+// addStats counts a finished game into the lifetime statistics and writes
+// them to disk
+func (s *Server) addStats(g *model.GameState) error {
+	if g.Summary == nil {
+		return nil
+	}
+	s.statsMu.Lock()
+	defer s.statsMu.Unlock()
+	s.lifetime.add(g, *g.Summary)
+	if s.lifetime.Modes == nil {
+		s.lifetime.Modes = map[string]*Totals{}
+	}
+	mode := s.lifetime.Modes[g.Mode.ID]
+	if mode == nil {
+		mode = &Totals{}
+		s.lifetime.Modes[g.Mode.ID] = mode
+	}
+	mode.add(g, *g.Summary)
+	if s.dataDir == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(s.lifetime, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := s.statsPath()
+	if err := os.WriteFile(path+".tmp", data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// StatsHandler serves GET /stats
+func (s *Server) StatsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, newAPIError("method_not_allowed", "method not allowed"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.Stats())
+}
@@ -0,0 +1,291 @@
+// Command tetris-env runs games step by step for reinforcement learning. It
+// reads one JSON request per line on stdin and writes one JSON reply per line
+// on stdout; logs go to stderr. Nothing happens between requests: there is no
+// gravity, lock delay or clock, so time limits never end a game.
+//
+// Requests act on a batch of games. Singular fields address a batch of one
+// and get singular replies:
+//
+//	{"cmd":"reset","seeds":[1,2],"mode":"sprint","placements":true}
+//	  -> {"observations":[...]}
+//	{"cmd":"step","actions":["left",3]}
+//	  -> {"observations":[...],"rewards":[...],"dones":[...]}
+//	{"cmd":"reset","seed":1}                 -> {"observation":{...}}
+//	{"cmd":"step","action":"drop"}           -> {"observation":{...},"reward":0,"done":false}
+//
+// An action is an input ("left", "right", "down", "rotate", "drop", "hold")
+// or the index of a placement in the last observation, which lists them when
+// the reset asked for placements. The reward is the score gained by the step.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+
+	"tetris-desktop/backend/model"
+	"tetris-desktop/backend/server"
+)
+
+type request struct {
+	Cmd        string            `json:"cmd"`
+	Seed       *int64            `json:"seed,omitempty"`
+	Seeds      []int64           `json:"seeds,omitempty"`
+	Mode       string            `json:"mode,omitempty"`
+	Lines      int               `json:"lines,omitempty"`
+	Minutes    int               `json:"minutes,omitempty"`
+	Placements bool              `json:"placements,omitempty"`
+	Action     json.RawMessage   `json:"action,omitempty"`
+	Actions    []json.RawMessage `json:"actions,omitempty"`
+}
+
+type reply struct {
+	Observation  *observation  `json:"observation,omitempty"`
+	Reward       *int          `json:"reward,omitempty"`
+	Done         *bool         `json:"done,omitempty"`
+	Observations []observation `json:"observations,omitempty"`
+	Rewards      []int         `json:"rewards,omitempty"`
+	Dones        []bool        `json:"dones,omitempty"`
+	Error        string        `json:"error,omitempty"`
+}
+
+// piece is the falling piece: its shape matrix placed at X, Y on the board
+type piece struct {
+	ID          int   `json:"id"`
+	Shape       []int `json:"shape"`
+	X           int   `json:"x"`
+	Y           int   `json:"y"`
+	Orientation int   `json:"orientation"`
+}
+
+type observation struct {
+	Board      [][]int           `json:"board"`  // colour per cell, 0 when empty
+	Hidden     int               `json:"hidden"` // buffer rows at the top of Board
+	Piece      piece             `json:"piece"`
+	Next       []int             `json:"next"` // piece ids in the next queue
+	Hold       int               `json:"hold"` // held piece id, 0 when empty
+	HoldUsed   bool              `json:"holdUsed"`
+	Score      int               `json:"score"`
+	Lines      int               `json:"lines"`
+	Level      int               `json:"level"`
+	Pieces     int               `json:"pieces"`
+	TopOut     string            `json:"topOut,omitempty"`
+	Completed  bool              `json:"completed"`
+	Placements []model.Placement `json:"placements,omitempty"`
+}
+
+// env is one game of the batch
+type env struct {
+	game       *model.Game
+	placements []model.Placement
+}
+
+func main() {
+	modesFile := flag.String("modes", "", "modes file to load instead of the built-in modes")
+	piecesFile := flag.String("pieces", "", "piece set file to add to the built-in sets")
+	flag.Parse()
+
+	srv := server.New()
+	if *piecesFile != "" {
+		if err := srv.LoadPieceSetsFile(*piecesFile); err != nil {
+			log.Fatal("Invalid piece set file: ", err)
+		}
+	}
+	if *modesFile != "" {
+		if err := srv.LoadModesFile(*modesFile); err != nil {
+			log.Fatal("Invalid modes file: ", err)
+		}
+	}
+
+	var envs []*env
+	withPlacements := false
+	in := bufio.NewScanner(os.Stdin)
+	in.Buffer(make([]byte, 1<<20), 1<<26)
+	out := json.NewEncoder(os.Stdout)
+
+	for in.Scan() {
+		var req request
+		var rep reply
+		if err := json.Unmarshal(in.Bytes(), &req); err != nil {
+			rep.Error = "invalid request: " + err.Error()
+			out.Encode(&rep)
+			continue
+		}
+
+		switch req.Cmd {
+		case "reset":
+			seeds := req.Seeds
+			if req.Seed != nil {
+				seeds = []int64{*req.Seed}
+			}
+			if len(seeds) == 0 {
+				rep.Error = "reset needs seed or seeds"
+				break
+			}
+			mode := srv.Mode(req.Mode, req.Lines, req.Minutes)
+			withPlacements = req.Placements
+			envs = make([]*env, len(seeds))
+			for i, seed := range seeds {
+				envs[i] = &env{game: model.NewSeededGame(mode, seed)}
+			}
+			obs := observeAll(envs, withPlacements)
+			if req.Seed != nil {
+				rep.Observation = &obs[0]
+			} else {
+				rep.Observations = obs
+			}
+
+		case "step":
+			actions := req.Actions
+			if req.Action != nil {
+				actions = []json.RawMessage{req.Action}
+			}
+			if len(actions) != len(envs) {
+				rep.Error = "step needs one action per game"
+				break
+			}
+			parsed := make([]action, len(actions))
+			var errs []error
+			for i, raw := range actions {
+				a, err := parseAction(raw)
+				if err == nil && a.input == "" && (a.index < 0 || a.index >= len(envs[i].placements)) {
+					err = errors.New("no such placement")
+				}
+				if err != nil {
+					errs = append(errs, fmt.Errorf("game %d: %w", i, err))
+				}
+				parsed[i] = a
+			}
+			if err := errors.Join(errs...); err != nil {
+				rep.Error = err.Error()
+				break
+			}
+			rewards := make([]int, len(envs))
+			parallel(envs, func(i int, e *env) {
+				rewards[i] = e.step(parsed[i])
+			})
+			obs := observeAll(envs, withPlacements)
+			dones := make([]bool, len(envs))
+			for i, o := range obs {
+				dones[i] = o.TopOut != "" || o.Completed
+			}
+			if req.Action != nil {
+				rep.Observation, rep.Reward, rep.Done = &obs[0], &rewards[0], &dones[0]
+			} else {
+				rep.Observations, rep.Rewards, rep.Dones = obs, rewards, dones
+			}
+
+		default:
+			rep.Error = "unknown cmd " + req.Cmd
+		}
+		if err := out.Encode(&rep); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if err := in.Err(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// action is a parsed step action: an input, or a placement index when the
+// input is empty
+type action struct {
+	input string
+	index int
+}
+
+func parseAction(raw json.RawMessage) (action, error) {
+	var a action
+	if json.Unmarshal(raw, &a.input) == nil {
+		switch a.input {
+		case model.InputLeft, model.InputRight, model.InputDown, model.InputRotate, model.InputDrop, "hold":
+			return a, nil
+		}
+		return a, errors.New("unknown action " + a.input)
+	}
+	if json.Unmarshal(raw, &a.index) == nil {
+		return a, nil
+	}
+	return a, errors.New("action must be an input or a placement index")
+}
+
+// step applies one action and returns the score it gained. Actions on a
+// finished game do nothing.
+func (e *env) step(a action) int {
+	before := e.game.Snapshot().Score
+	switch a.input {
+	case model.InputLeft:
+		e.game.MoveLeft()
+	case model.InputRight:
+		e.game.MoveRight()
+	case model.InputDown:
+		e.game.MoveDown()
+	case model.InputRotate:
+		e.game.Rotate()
+	case model.InputDrop:
+		e.game.Drop()
+	case "hold":
+		e.game.Hold()
+	case "":
+		e.game.Place(e.placements[a.index])
+	}
+	// agents step without time passing, so delays would never end
+	e.game.SkipDelays()
+	return e.game.Snapshot().Score - before
+}
+
+func (e *env) observe(placements bool) observation {
+	s := e.game.Snapshot()
+	next, held := e.game.Upcoming()
+	e.placements = nil
+	if placements {
+		e.placements = e.game.Placements()
+	}
+	return observation{
+		Board:  s.ColorGrid(),
+		Hidden: s.Hidden,
+		Piece: piece{
+			ID:          s.PieceID,
+			Shape:       s.Piece,
+			X:           s.X,
+			Y:           s.Y,
+			Orientation: s.Orientation,
+		},
+		Next:       next,
+		Hold:       held,
+		HoldUsed:   s.HoldUsed,
+		Score:      s.Score,
+		Lines:      s.Lines,
+		Level:      s.Level,
+		Pieces:     s.Pieces,
+		TopOut:     s.TopOut,
+		Completed:  s.Completed,
+		Placements: e.placements,
+	}
+}
+
+func observeAll(envs []*env, placements bool) []observation {
+	obs := make([]observation, len(envs))
+	parallel(envs, func(i int, e *env) {
+		obs[i] = e.observe(placements)
+	})
+	return obs
+}
+
+// parallel runs f for every game of the batch at once
+func parallel(envs []*env, f func(i int, e *env)) {
+	var wg sync.WaitGroup
+	for i, e := range envs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f(i, e)
+		}()
+	}
+	wg.Wait()
+}
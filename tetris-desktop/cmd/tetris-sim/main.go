@@ -0,0 +1,318 @@
+// Command tetris-sim plays many seeded games with a computer player and
+// reports how they went, for tuning modes, randomizers and the AI. Games are
+// played without a clock, so time limits never end them; -max-pieces does.
+//
+// CSV output has one row per game. JSON output adds a summary with the
+// distributions of score, lines and game length, the longest drought of each
+// piece and how games ended. A short summary always goes to stderr.
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"os"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"tetris-desktop/backend/ai"
+	"tetris-desktop/backend/model"
+	"tetris-desktop/backend/server"
+)
+
+// ends of a game besides the top-out causes
+const (
+	endCompleted = "completed"
+	endLimit     = "piece limit"
+)
+
+// result is how one game went
+type result struct {
+	Seed   int64  `json:"seed"`
+	Score  int    `json:"score"`
+	Lines  int    `json:"lines"`
+	Pieces int    `json:"pieces"`
+	Level  int    `json:"level"`
+	End    string `json:"end"`
+	// Droughts holds, per piece id from 1, the most pieces dealt in a row
+	// without that piece
+	Droughts []int `json:"droughts"`
+}
+
+// stats summarises one number over all games
+type stats struct {
+	Min    int     `json:"min"`
+	Mean   float64 `json:"mean"`
+	Median int     `json:"median"`
+	P90    int     `json:"p90"`
+	Max    int     `json:"max"`
+}
+
+type summary struct {
+	Games    int            `json:"games"`
+	Score    stats          `json:"score"`
+	Lines    stats          `json:"lines"`
+	Pieces   stats          `json:"pieces"`
+	Droughts []stats        `json:"droughts"` // per piece id from 1
+	Ends     map[string]int `json:"ends"`
+}
+
+// player picks the next move, or false when it has none
+type player func(g *model.Game) (ai.Move, bool)
+
+func main() {
+	games := flag.Int("games", 1000, "number of games to play")
+	seed := flag.Int64("seed", 1, "seed of the first game; game i uses seed+i")
+	mode := flag.String("mode", "", "mode id, default mode when empty")
+	lines := flag.Int("lines", 0, "line goal, one of the mode's choices")
+	minutes := flag.Int("minutes", 0, "time limit, one of the mode's choices")
+	botName := flag.String("bot", "ai", "player: ai or random")
+	level := flag.String("level", "", "ai difficulty: "+strings.Join(ai.Levels, ", ")+"; sets the look-ahead")
+	lookAhead := flag.Int("lookahead", 0, "preview pieces the ai searches through, without -level")
+	maxPieces := flag.Int("max-pieces", 1000, "end a game after this many pieces")
+	workers := flag.Int("workers", runtime.NumCPU(), "games played at once")
+	format := flag.String("format", "csv", "output format: csv or json")
+	outFile := flag.String("out", "", "write results to this file instead of stdout")
+	modesFile := flag.String("modes", "", "modes file to load instead of the built-in modes")
+	piecesFile := flag.String("pieces", "", "piece set file to add to the built-in sets")
+	flag.Parse()
+
+	srv := server.New()
+	if *piecesFile != "" {
+		if err := srv.LoadPieceSetsFile(*piecesFile); err != nil {
+			log.Fatal("Invalid piece set file: ", err)
+		}
+	}
+	if *modesFile != "" {
+		if err := srv.LoadModesFile(*modesFile); err != nil {
+			log.Fatal("Invalid modes file: ", err)
+		}
+	}
+	if *format != "csv" && *format != "json" {
+		log.Fatal("Unknown format ", *format)
+	}
+
+	var play func(seed int64) player
+	switch *botName {
+	case "ai":
+		if _, ok := ai.Level(*level); *level != "" && !ok {
+			log.Fatal("Unknown level ", *level)
+		}
+		play = func(int64) player {
+			b := ai.New()
+			b.LookAhead = *lookAhead
+			if *level != "" {
+				b, _ = ai.Level(*level)
+			}
+			return b.Best
+		}
+	case "random":
+		play = func(seed int64) player {
+			rng := rand.New(rand.NewSource(seed))
+			return func(g *model.Game) (ai.Move, bool) {
+				ps := g.Placements()
+				if len(ps) == 0 {
+					return ai.Move{}, false
+				}
+				return ai.Move{Placement: ps[rng.Intn(len(ps))]}, true
+			}
+		}
+	default:
+		log.Fatal("Unknown bot ", *botName)
+	}
+
+	m := srv.Mode(*mode, *lines, *minutes)
+	results := make([]result, *games)
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range max(*workers, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				s := *seed + int64(i)
+				results[i] = simulate(model.NewSeededGame(m, s), play(s), *maxPieces)
+			}
+		}()
+	}
+	for i := range results {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	out := io.Writer(os.Stdout)
+	if *outFile != "" {
+		f, err := os.Create(*outFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		out = f
+	}
+	sum := summarize(results)
+	var err error
+	if *format == "json" {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(struct {
+			Mode    string   `json:"mode"`
+			Bot     string   `json:"bot"`
+			Summary summary  `json:"summary"`
+			Games   []result `json:"games"`
+		}{m.ID, *botName, sum, results})
+	} else {
+		err = writeCSV(out, results)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Fprintf(os.Stderr, "%d games of %s: score mean %.0f median %d, lines mean %.1f, pieces mean %.1f, ends %v\n",
+		sum.Games, m.Name, sum.Score.Mean, sum.Score.Median, sum.Lines.Mean, sum.Pieces.Mean, sum.Ends)
+}
+
+// simulate plays g to its end or the piece limit
+func simulate(g *model.Game, next player, maxPieces int) result {
+	s := g.Snapshot()
+	queue, _ := g.Upcoming()
+	dealt := append([]int{s.PieceID}, queue...)
+
+	for s.Pieces < maxPieces && !s.GameOver && !s.Completed {
+		move, ok := next(g)
+		if !ok {
+			break
+		}
+		_, held := g.Upcoming()
+		if move.Hold && g.Hold() && held == 0 {
+			// the first hold takes the next piece, dealing a new one
+			queue, _ = g.Upcoming()
+			dealt = append(dealt, queue[len(queue)-1])
+		}
+		if !g.Place(move.Placement) {
+			break
+		}
+		queue, _ = g.Upcoming()
+		dealt = append(dealt, queue[len(queue)-1])
+		s = g.Snapshot()
+	}
+
+	r := result{
+		Seed:     s.Seed,
+		Score:    s.Score,
+		Lines:    s.Lines,
+		Pieces:   s.Pieces,
+		Level:    s.Level,
+		End:      s.TopOut,
+		Droughts: droughts(dealt, setSize(s.Mode)),
+	}
+	switch {
+	case s.Completed:
+		r.End = endCompleted
+	case !s.GameOver:
+		r.End = endLimit
+	}
+	return r
+}
+
+// setSize returns the number of pieces in the mode's set
+func setSize(m model.GameMode) int {
+	set, ok := model.PieceSetByName(m.PieceSet)
+	if !ok {
+		set, _ = model.PieceSetByName(model.DefaultPieceSet)
+	}
+	return len(set.Pieces)
+}
+
+// droughts returns, per piece id from 1, the longest run of dealt pieces
+// without it
+func droughts(dealt []int, pieces int) []int {
+	longest := make([]int, pieces)
+	last := make([]int, pieces)
+	for i := range last {
+		last[i] = -1
+	}
+	for i, id := range dealt {
+		if id < 1 || id > pieces {
+			continue
+		}
+		longest[id-1] = max(longest[id-1], i-last[id-1]-1)
+		last[id-1] = i
+	}
+	for id := range longest {
+		longest[id] = max(longest[id], len(dealt)-last[id]-1)
+	}
+	return longest
+}
+
+func summarize(results []result) summary {
+	sum := summary{Games: len(results), Ends: map[string]int{}}
+	if len(results) == 0 {
+		return sum
+	}
+	field := func(f func(r result) int) stats {
+		vals := make([]int, len(results))
+		for i, r := range results {
+			vals[i] = f(r)
+		}
+		return describe(vals)
+	}
+	sum.Score = field(func(r result) int { return r.Score })
+	sum.Lines = field(func(r result) int { return r.Lines })
+	sum.Pieces = field(func(r result) int { return r.Pieces })
+	for id := range results[0].Droughts {
+		sum.Droughts = append(sum.Droughts, field(func(r result) int { return r.Droughts[id] }))
+	}
+	for _, r := range results {
+		sum.Ends[r.End]++
+	}
+	return sum
+}
+
+func describe(vals []int) stats {
+	slices.Sort(vals)
+	total := 0
+	for _, v := range vals {
+		total += v
+	}
+	return stats{
+		Min:    vals[0],
+		Mean:   float64(total) / float64(len(vals)),
+		Median: vals[len(vals)/2],
+		P90:    vals[len(vals)*9/10],
+		Max:    vals[len(vals)-1],
+	}
+}
+
+func writeCSV(out io.Writer, results []result) error {
+	w := csv.NewWriter(out)
+	header := []string{"seed", "score", "lines", "pieces", "level", "end"}
+	if len(results) > 0 {
+		for id := range results[0].Droughts {
+			header = append(header, "drought_"+strconv.Itoa(id+1))
+		}
+	}
+	w.Write(header)
+	for _, r := range results {
+		row := []string{
+			strconv.FormatInt(r.Seed, 10),
+			strconv.Itoa(r.Score),
+			strconv.Itoa(r.Lines),
+			strconv.Itoa(r.Pieces),
+			strconv.Itoa(r.Level),
+			r.End,
+		}
+		for _, d := range r.Droughts {
+			row = append(row, strconv.Itoa(d))
+		}
+		w.Write(row)
+	}
+	w.Flush()
+	return w.Error()
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bufio"
+	"io"
+)
+
+// keys that are not plain characters
+const (
+	keyUp rune = -1 - iota
+	keyDown
+	keyRight
+	keyLeft
+	keyInterrupt
+)
+
+// readKeys decodes key presses from a terminal in raw mode, turning arrow
+// key escape sequences into the key constants
+func readKeys(r io.Reader) <-chan rune {
+	keys := make(chan rune)
+	go func() {
+		defer close(keys)
+		in := bufio.NewReader(r)
+		for {
+			c, _, err := in.ReadRune()
+			if err != nil {
+				return
+			}
+			switch c {
+			case 3: // Ctrl-C when the terminal does not turn it into a signal
+				keys <- keyInterrupt
+				continue
+			case 0x1b:
+			default:
+				keys <- c
+				continue
+			}
+			// ESC [ A..D, or ESC O A..D in application cursor mode
+			if b, _ := in.ReadByte(); b != '[' && b != 'O' {
+				continue
+			}
+			switch b, _ := in.ReadByte(); b {
+			case 'A':
+				keys <- keyUp
+			case 'B':
+				keys <- keyDown
+			case 'C':
+				keys <- keyRight
+			case 'D':
+				keys <- keyLeft
+			}
+		}
+	}()
+	return keys
+}
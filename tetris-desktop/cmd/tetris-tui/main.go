@@ -0,0 +1,150 @@
+// Command tetris-tui plays against the game server in a terminal. It connects
+// to the same websocket as the desktop frontend and draws the board, falling
+// piece, ghost, hold and next queue with ANSI escapes.
+//
+// Keys: arrows or WASD move, up/W/X rotate, space drops, C holds, P pauses
+// and resumes, R restarts and Q quits.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+
+	"github.com/gorilla/websocket"
+
+	"tetris-desktop/backend/model"
+	"tetris-desktop/backend/server"
+)
+
+// message is what the client sends, as on /ws
+type message struct {
+	Type    string `json:"type"`
+	Dir     string `json:"dir,omitempty"`
+	Mode    string `json:"mode,omitempty"`
+	Lines   int    `json:"lines,omitempty"`
+	Minutes int    `json:"minutes,omitempty"`
+}
+
+func main() {
+	addr := flag.String("addr", "ws://localhost:8081/ws", "websocket address of the game server")
+	mode := flag.String("mode", "", "mode id, the server's default when empty")
+	lines := flag.Int("lines", 0, "line goal, one of the mode's choices")
+	minutes := flag.Int("minutes", 0, "time limit, one of the mode's choices")
+	token := flag.String("token", os.Getenv("TETRIS_TOKEN"), "access token the server printed at launch, default $TETRIS_TOKEN")
+	flag.Parse()
+
+	u, err := url.Parse(*addr)
+	if err != nil {
+		log.Fatal("Invalid address: ", err)
+	}
+	q := u.Query()
+	q.Set("mode", *mode)
+	q.Set("lines", strconv.Itoa(*lines))
+	q.Set("minutes", strconv.Itoa(*minutes))
+	u.RawQuery = q.Encode()
+
+	header := http.Header{}
+	if *token != "" {
+		header.Set(server.TokenHeader, *token)
+	}
+	conn, _, err := websocket.DefaultDialer.Dial(u.String(), header)
+	if err != nil {
+		log.Fatal("Cannot connect to ", *addr, ": ", err)
+	}
+	defer conn.Close()
+
+	restore, err := makeRaw()
+	if err != nil {
+		log.Fatal("Cannot set up the terminal: ", err)
+	}
+	fmt.Print(hideCursor + clearScreen)
+	done := func() {
+		fmt.Print(showCursor + reset + "\n")
+		restore()
+	}
+	defer done()
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		<-interrupt
+		done()
+		os.Exit(1)
+	}()
+
+	var writeMu sync.Mutex
+	send := func(msg message) {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		conn.WriteJSON(msg)
+	}
+
+	quit := make(chan struct{})
+	go func() {
+		defer close(quit)
+		for key := range readKeys(os.Stdin) {
+			switch key {
+			case keyLeft, 'a', 'A':
+				send(message{Type: "move", Dir: "left"})
+			case keyRight, 'd', 'D':
+				send(message{Type: "move", Dir: "right"})
+			case keyDown, 's', 'S':
+				send(message{Type: "move", Dir: "down"})
+			case keyUp, 'w', 'W', 'x', 'X':
+				send(message{Type: "rotate"})
+			case ' ':
+				send(message{Type: "drop"})
+			case 'c', 'C':
+				send(message{Type: "hold"})
+			case 'p', 'P':
+				send(message{Type: "pause/resume"})
+			case 'r', 'R':
+				send(message{Type: "restart", Mode: *mode, Lines: *lines, Minutes: *minutes})
+			case 'q', 'Q', keyInterrupt:
+				return
+			}
+		}
+	}()
+
+	states := make(chan *model.GameState)
+	go func() {
+		defer close(states)
+		for {
+			var raw json.RawMessage
+			if err := conn.ReadJSON(&raw); err != nil {
+				return
+			}
+			// refused messages, such as inputs over the rate limit, are skipped
+			var refused struct{ Type string }
+			if json.Unmarshal(raw, &refused) == nil && refused.Type == "error" {
+				continue
+			}
+			state := new(model.GameState)
+			if err := json.Unmarshal(raw, state); err != nil {
+				return
+			}
+			states <- state
+		}
+	}()
+
+	for {
+		select {
+		case <-quit:
+			return
+		case state, ok := <-states:
+			if !ok {
+				done()
+				log.Fatal("Connection to the server closed")
+			}
+			fmt.Print(render(state))
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"tetris-desktop/backend/model"
+)
+
+const (
+	clearScreen = "\x1b[2J"
+	home        = "\x1b[H"
+	clearLine   = "\x1b[K"
+	hideCursor  = "\x1b[?25l"
+	showCursor  = "\x1b[?25h"
+	reset       = "\x1b[0m"
+	dim         = "\x1b[90m"
+)
+
+// colors match the frontend's colour manager
+var colors = map[int][3]int{
+	1:  {0x00, 0xd4, 0xd4}, // I
+	2:  {0xf0, 0xc0, 0x00}, // O
+	3:  {0xb0, 0x30, 0xf0}, // T
+	4:  {0x00, 0xd4, 0x00}, // S
+	5:  {0xf0, 0x30, 0x30}, // Z
+	6:  {0x30, 0x50, 0xf0}, // J
+	7:  {0xf0, 0x88, 0x20}, // L
+	8:  {0xff, 0x69, 0xb4},
+	9:  {0x00, 0xd4, 0x00},
+	10: {0xf0, 0xc0, 0x00},
+	11: {0x30, 0x50, 0xf0},
+	12: {0xf0, 0x30, 0x30},
+	13: {0x80, 0x80, 0x80}, // garbage
+}
+
+// block draws one cell two columns wide in colour c
+func block(c int) string {
+	rgb, ok := colors[c]
+	if !ok {
+		rgb = [3]int{0x66, 0x66, 0x66}
+	}
+	return fmt.Sprintf("\x1b[48;2;%d;%d;%dm  %s", rgb[0], rgb[1], rgb[2], reset)
+}
+
+// render draws a whole frame for s
+func render(s *model.GameState) string {
+	grid := s.ColorGrid()
+	ghostCells := map[[2]int]bool{}
+	if !s.GameOver && !s.Completed && s.Piece != nil {
+		if s.Mode.GhostPiece {
+			gy := s.Y
+			for !collides(s, grid, s.X, gy+1) {
+				gy++
+			}
+			eachCell(s.Piece, func(x, y, _ int) {
+				ghostCells[[2]int{s.X + x, gy + y}] = true
+			})
+		}
+		eachCell(s.Piece, func(x, y, v int) {
+			if bx, by := s.X+x, s.Y+y; by >= 0 && by < len(grid) && bx >= 0 && bx < s.Width {
+				grid[by][bx] = v
+			}
+		})
+	}
+
+	var board []string
+	border := dim + "+" + strings.Repeat("--", s.Width) + "+" + reset
+	board = append(board, border)
+	for y := s.Hidden; y < len(grid); y++ {
+		var b strings.Builder
+		b.WriteString(dim + "|" + reset)
+		for x, v := range grid[y] {
+			switch {
+			case v != 0:
+				b.WriteString(block(v))
+			case ghostCells[[2]int{x, y}]:
+				b.WriteString(dim + "[]" + reset)
+			default:
+				b.WriteString(dim + " ." + reset)
+			}
+		}
+		b.WriteString(dim + "|" + reset)
+		board = append(board, b.String())
+	}
+	board = append(board, border)
+
+	side := []string{
+		s.Mode.Name,
+		"",
+		fmt.Sprintf("Score  %d", s.Score),
+		fmt.Sprintf("Lines  %d", s.Lines),
+		fmt.Sprintf("Level  %d", s.Level),
+		"Time   " + clockTime(s),
+		"",
+	}
+	if s.Mode.Hold {
+		side = append(side, "Hold")
+		side = append(side, shape(s.Held)...)
+		side = append(side, "")
+	}
+	if s.Mode.NextPreview {
+		side = append(side, "Next")
+		for _, p := range s.Next {
+			side = append(side, shape(p)...)
+			side = append(side, "")
+		}
+	}
+	switch {
+	case s.Completed:
+		side = append(side, "", "Complete! R to play again")
+	case s.GameOver:
+		side = append(side, "", "Game over ("+s.TopOut+") - R to restart")
+	case s.Paused:
+		side = append(side, "", "Paused - P to resume")
+	}
+
+	var out strings.Builder
+	out.WriteString(home)
+	for i := range max(len(board), len(side)) {
+		line := strings.Repeat(" ", 2*s.Width+2)
+		if i < len(board) {
+			line = board[i]
+		}
+		out.WriteString(line + "  ")
+		if i < len(side) {
+			out.WriteString(side[i])
+		}
+		out.WriteString(clearLine + "\r\n")
+	}
+	out.WriteString("\x1b[J")
+	return out.String()
+}
+
+// clockTime shows the time left in timed modes and the time played otherwise
+func clockTime(s *model.GameState) string {
+	d := time.Duration(s.Elapsed) * time.Millisecond
+	if s.Mode.Goal.TimeLimit > 0 {
+		d = max(time.Duration(s.Mode.Goal.TimeLimit)*time.Millisecond-d, 0)
+	}
+	return fmt.Sprintf("%d:%04.1f", int(d.Minutes()), (d % time.Minute).Seconds())
+}
+
+// shape draws a piece matrix, skipping its empty rows
+func shape(p []int) []string {
+	n := pieceSize(p)
+	var lines []string
+	for y := range n {
+		var b strings.Builder
+		filled := false
+		for x := range n {
+			if v := p[y*n+x]; v != 0 {
+				b.WriteString(block(v))
+				filled = true
+			} else {
+				b.WriteString("  ")
+			}
+		}
+		if filled {
+			lines = append(lines, b.String())
+		}
+	}
+	return lines
+}
+
+func pieceSize(p []int) int {
+	n := 0
+	for n*n < len(p) {
+		n++
+	}
+	return n
+}
+
+// eachCell calls f for every filled cell of a piece matrix
+func eachCell(p []int, f func(x, y, v int)) {
+	n := pieceSize(p)
+	for i, v := range p {
+		if v != 0 {
+			f(i%n, i/n, v)
+		}
+	}
+}
+
+// collides reports whether the falling piece would overlap the stack or
+// leave the board at x, y
+func collides(s *model.GameState, grid [][]int, x, y int) bool {
+	hit := false
+	eachCell(s.Piece, func(px, py, _ int) {
+		bx, by := x+px, y+py
+		if bx < 0 || bx >= s.Width || by < 0 || by >= len(grid) || grid[by][bx] != 0 {
+			hit = true
+		}
+	})
+	return hit
+}
@@ -0,0 +1,29 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// makeRaw switches the terminal to unbuffered input without echo using
+// stty, returning a function that restores the previous settings
+func makeRaw() (func(), error) {
+	stty := func(args ...string) *exec.Cmd {
+		cmd := exec.Command("stty", args...)
+		cmd.Stdin = os.Stdin
+		return cmd
+	}
+	saved, err := stty("-g").Output()
+	if err != nil {
+		return nil, err
+	}
+	if err := stty("-icanon", "-echo", "min", "1").Run(); err != nil {
+		return nil, err
+	}
+	return func() {
+		stty(strings.TrimSpace(string(saved))).Run()
+	}, nil
+}
@@ -0,0 +1,52 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// console mode flags
+const (
+	enableProcessedInput            = 0x0001
+	enableLineInput                 = 0x0002
+	enableEchoInput                 = 0x0004
+	enableVirtualTerminalInput      = 0x0200
+	enableVirtualTerminalProcessing = 0x0004
+)
+
+var setConsoleMode = syscall.NewLazyDLL("kernel32.dll").NewProc("SetConsoleMode")
+
+// makeRaw switches the console to unbuffered input without echo, with arrow
+// keys sent as escape sequences and ANSI output enabled, returning a function
+// that restores the previous modes
+func makeRaw() (func(), error) {
+	in := syscall.Handle(os.Stdin.Fd())
+	out := syscall.Handle(os.Stdout.Fd())
+	var inMode, outMode uint32
+	if err := syscall.GetConsoleMode(in, &inMode); err != nil {
+		return nil, err
+	}
+	if err := syscall.GetConsoleMode(out, &outMode); err != nil {
+		return nil, err
+	}
+	set := func(h syscall.Handle, mode uint32) error {
+		if ok, _, err := setConsoleMode.Call(uintptr(h), uintptr(mode)); ok == 0 {
+			return err
+		}
+		return nil
+	}
+	raw := inMode&^(enableProcessedInput|enableLineInput|enableEchoInput) | enableVirtualTerminalInput
+	if err := set(in, raw); err != nil {
+		return nil, err
+	}
+	if err := set(out, outMode|enableVirtualTerminalProcessing); err != nil {
+		set(in, inMode)
+		return nil, err
+	}
+	return func() {
+		set(in, inMode)
+		set(out, outMode)
+	}, nil
+}
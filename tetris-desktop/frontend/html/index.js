@@ -1,3 +1,6 @@
+import { desktop, backendURL, settingsReady } from '../src/tetris/backend.js';
+import { AIController } from '../src/tetris/controllers/aiController.js';
+
 // Initialize menu buttons when DOM is ready
 function initMenu() {
     const startBtn = document.getElementById('startBtn');
@@ -5,24 +8,14 @@ function initMenu() {
     const quitBtn = document.getElementById('quitBtn');
 
     if (startBtn) {
-        startBtn.addEventListener('click', async () => {
-            try {
-                await fetch('http://localhost:8081/start', { method: 'POST' });
-            } catch (e) {
-                console.warn('start fetch failed', e);
-            }
+        startBtn.addEventListener('click', () => {
             // go to game
             window.location.href = 'tetris.html';
         });
     }
 
     if (settingsBtn) {
-        settingsBtn.addEventListener('click', async () => {
-            try {
-                await fetch('http://localhost:8081/settings', { method: 'POST' });
-            } catch (e) {
-                console.warn('settings fetch failed', e);
-            }
+        settingsBtn.addEventListener('click', () => {
             // go to settings
             window.location.href = 'settings.html';
         });
@@ -30,14 +23,35 @@ function initMenu() {
 
     if (quitBtn) {
         quitBtn.addEventListener('click', () => {
-            window.runtime.Quit();
+            if (desktop) {
+                window.runtime.Quit();
+            } else {
+                fetch(backendURL + '/quit', { method: 'POST' })
+                    .catch(e => console.warn('quit fetch failed', e));
+            }
         });
     }
 }
 
+// Let the AI play the selected mode beside the menu, starting over after
+// every game, unless the demo is turned off in the settings
+function initDemo() {
+    const panel = document.getElementById('demo');
+    if (!panel || localStorage.getItem('aiDemo') === '0') return;
+    panel.hidden = false;
+    const mode = localStorage.getItem('gameMode') || '';
+    const demo = new AIController({ canvasId: 'demoTetris', loop: true });
+    demo.start({ mode, level: 'normal' });
+    window.addEventListener('pagehide', () => demo.stop());
+}
+
 // Run when DOM is ready
 if (document.readyState === 'loading') {
-    window.addEventListener('DOMContentLoaded', initMenu);
+    window.addEventListener('DOMContentLoaded', () => {
+        initMenu();
+        settingsReady.then(initDemo);
+    });
 } else {
     initMenu();
+    settingsReady.then(initDemo);
 }
\ No newline at end of file
@@ -1,4 +1,5 @@
 import { soundManager } from '../src/tetris/sounds.js';
+import { getModes, getBackendURL, getBackendToken, pushSettings } from '../src/tetris/backend.js';
 
 // Initialize settings only when DOM is ready
 function initSettings() {
@@ -12,8 +13,16 @@ function initSettings() {
     const musicVolumeDisplay = document.getElementById('musicVolumeDisplay');
     const goBackBtn = document.getElementById('goBackBtn');
     const difficultyRadios = document.querySelectorAll('input[name="difficulty"]');
+    const sprintLines = document.getElementById('sprintLines');
+    const ultraMinutes = document.getElementById('ultraMinutes');
+    const marathonLines = document.getElementById('marathonLines');
+    const digLines = document.getElementById('digLines');
     const savedMode = localStorage.getItem('gameMode') || 'beginner';
 
+    // Offer custom modes from the server's modes file
+    addCustomModes(savedMode);
+    showBackendURL();
+
     // Set the initial checked state based on saved mode
     difficultyRadios.forEach(radio => {
       if (radio.value === savedMode) {
@@ -35,6 +44,43 @@ function initSettings() {
       });
     });
 
+    // Goal length for sprint, ultra, marathon and dig
+    [[sprintLines, 'sprintLines', '40'], [ultraMinutes, 'ultraMinutes', '3'],
+        [marathonLines, 'marathonLines', '150'], [digLines, 'digLines', '18']]
+        .forEach(([select, key, fallback]) => {
+            if (!select) return;
+            select.value = localStorage.getItem(key) || fallback;
+            select.addEventListener('change', () => {
+                localStorage.setItem(key, select.value);
+            });
+        });
+
+    // AI opponent beside the player's board, off when empty
+    const aiOpponent = document.getElementById('aiOpponent');
+    if (aiOpponent) {
+        aiOpponent.value = localStorage.getItem('aiOpponent') || '';
+        aiOpponent.addEventListener('change', () => {
+            localStorage.setItem('aiOpponent', aiOpponent.value);
+        });
+    }
+
+    // Handling of held keys, used by the backend so saved straight away
+    [['das', 167, 1000], ['arr', 33, 500], ['sdf', 20, 100]].forEach(([key, fallback, limit]) => {
+        const input = document.getElementById(key);
+        if (!input) return;
+        input.value = localStorage.getItem(key) || fallback;
+        input.addEventListener('change', () => {
+            const n = Math.round(Number(input.value));
+            if (!Number.isFinite(n) || n < 0 || n > limit) {
+                input.value = localStorage.getItem(key) || fallback;
+                return;
+            }
+            input.value = n;
+            localStorage.setItem(key, String(n));
+            pushSettings();
+        });
+    });
+
     // Load current values
     const saved = localStorage.getItem('ghostPieceEnabled');
     if (ghostToggle) ghostToggle.checked = saved === '1';
@@ -63,6 +109,15 @@ function initSettings() {
         });
     }
 
+    // Save AI demo setting to be toggled
+    const aiDemoToggle = document.getElementById('aiDemoToggle');
+    if (aiDemoToggle) {
+        aiDemoToggle.checked = localStorage.getItem('aiDemo') !== '0';
+        aiDemoToggle.addEventListener('change', () => {
+            localStorage.setItem('aiDemo', aiDemoToggle.checked ? '1' : '0');
+        });
+    }
+
     // Save Tetrix setting to be toggled
     if (tetrixToggle) {
         tetrixToggle.addEventListener('change', () => {
@@ -114,6 +169,47 @@ function initSettings() {
     }
 }
 
+// Add a radio for every server mode the page doesn't already list
+async function addCustomModes(savedMode) {
+    let set;
+    try {
+        set = await getModes();
+    } catch (e) {
+        console.warn('modes fetch failed', e);
+        return;
+    }
+    const known = new Set([...document.querySelectorAll('input[name="difficulty"]')].map(r => r.value));
+    const anchor = document.getElementById('ghostToggle')?.closest('label');
+    set.modes.filter(m => !known.has(m.id)).forEach(m => {
+        const label = document.createElement('label');
+        const radio = document.createElement('input');
+        radio.type = 'radio';
+        radio.name = 'difficulty';
+        radio.value = m.id;
+        radio.checked = m.id === savedMode;
+        radio.addEventListener('change', () => {
+            localStorage.setItem('gameMode', m.id);
+        });
+        label.append(radio, document.createElement('span'), ' ' + m.name);
+        anchor.before(label);
+    });
+}
+
+// Tell the player where bots can connect, if anywhere
+async function showBackendURL() {
+    const el = document.getElementById('backendInfo');
+    if (!el) return;
+    try {
+        const url = await getBackendURL();
+        const token = await getBackendToken();
+        el.textContent = !url ? '' : token
+            ? 'Bots and clients connect to ' + url + ' with token ' + token
+            : 'Bots and clients connect to ' + url;
+    } catch (e) {
+        console.warn('backend address unknown', e);
+    }
+}
+
 // Run when DOM is ready
 if (document.readyState === 'loading') {
     window.addEventListener('DOMContentLoaded', initSettings);
@@ -0,0 +1,198 @@
+import { createWS } from './ws.js';
+
+// How pages talk to the game backend. Inside the desktop app they call the
+// Go methods Wails binds to window.go, so no network port is needed; pages
+// served by the backend itself (headless mode) use its HTTP API at the
+// address config.js sets, or the default port.
+const app = window.go && window.go.main && window.go.main.App;
+export const desktop = !!app;
+export const backendURL = window.tetrisBackend || 'http://localhost:8081';
+export const wsURL = backendURL.replace(/^http/, 'ws');
+
+// Where other clients such as bots reach this game's HTTP API. The desktop
+// app picks its port at launch and only serves it with --api, so ask it;
+// "" means no API is being served.
+export async function getBackendURL() {
+    if (desktop) return app.BackendURL();
+    return backendURL;
+}
+
+// The access token those clients must send. Pages in a browser never see
+// it: they are let in by the cookie set when the backend's address with the
+// token was opened.
+export async function getBackendToken() {
+    if (desktop) return app.BackendToken();
+    return '';
+}
+
+// query string selecting a leaderboard
+function boardQuery(board) {
+    const params = new URLSearchParams();
+    if (board.mode) params.set('mode', board.mode);
+    if (board.lines) params.set('lines', board.lines);
+    if (board.minutes) params.set('minutes', board.minutes);
+    return params.toString();
+}
+
+async function getJSON(path, options) {
+    const res = await fetch(backendURL + path, options);
+    if (!res.ok) throw new Error(path + ' returned ' + res.status);
+    return res.json();
+}
+
+// leaderboard for a mode and goal, in ranking order
+export async function getHighscores(board) {
+    if (desktop) return await app.Highscores(board.mode || '', board.lines || 0, board.minutes || 0) || [];
+    return getJSON('/highscores?' + boardQuery(board));
+}
+
+// offer a finished game for its leaderboard
+export async function postHighscore(entry) {
+    if (desktop) return app.SubmitHighscore(entry);
+    return getJSON('/highscores', {
+        method: 'POST',
+        headers: {'Content-Type': 'application/json'},
+        body: JSON.stringify(entry)
+    });
+}
+
+// modes on offer: { default, modes }
+export async function getModes() {
+    if (desktop) return app.Modes();
+    return getJSON('/modes');
+}
+
+// stored preferences, by localStorage key
+export async function getSettings() {
+    if (desktop) return app.Settings();
+    return getJSON('/settings');
+}
+
+export async function saveSettings(settings) {
+    if (desktop) return app.SaveSettings(settings);
+    return getJSON('/settings', {
+        method: 'POST',
+        headers: {'Content-Type': 'application/json'},
+        body: JSON.stringify(settings)
+    });
+}
+
+// saved games and recorded finished games, newest first
+export async function getSaves() {
+    if (desktop) return await app.Saves() || [];
+    return getJSON('/saves');
+}
+
+export async function getReplays() {
+    if (desktop) return await app.Replays() || [];
+    return getJSON('/replays');
+}
+
+// totals over every finished game, and by mode
+export async function getStats() {
+    if (desktop) return app.Stats();
+    return getJSON('/stats');
+}
+
+// query string of the params that are set
+function paramsQuery(params) {
+    const query = new URLSearchParams();
+    for (const [k, v] of Object.entries(params)) {
+        if (v) query.set(k, v);
+    }
+    return query.toString();
+}
+
+// Start a game session: params are mode, lines, minutes and optionally the
+// save to resume. States go to onState; the returned object sends inputs.
+export function connectGame(params, onState, onOpen, onClose) {
+    if (!desktop) {
+        return createWS(wsURL + '/ws?' + paramsQuery(params), onState, onOpen, onClose);
+    }
+    window.runtime.EventsOff('game:state');
+    window.runtime.EventsOn('game:state', onState);
+    app.StartGame({
+        mode: params.mode || '',
+        lines: params.lines || 0,
+        minutes: params.minutes || 0,
+        save: params.save || ''
+    });
+    if (onOpen) onOpen();
+    return {
+        send(msg) {
+            app.GameInput(msg);
+            return true;
+        },
+        isAvailable() { return true; },
+        close() {
+            window.runtime.EventsOff('game:state');
+            app.StopGame();
+        }
+    };
+}
+
+// Start a game the backend's AI plays, for a versus opponent or a demo:
+// params are mode, lines, minutes and level, its difficulty. States go to
+// onState; the returned object sends "pause/resume" and "restart".
+export function connectAI(params, onState, onOpen, onClose) {
+    if (!desktop) {
+        return createWS(wsURL + '/ai?' + paramsQuery(params), onState, onOpen, onClose);
+    }
+    window.runtime.EventsOff('ai:state');
+    window.runtime.EventsOn('ai:state', onState);
+    app.StartAI({
+        mode: params.mode || '',
+        lines: params.lines || 0,
+        minutes: params.minutes || 0,
+        level: params.level || ''
+    });
+    if (onOpen) onOpen();
+    return {
+        send(msg) {
+            app.AIInput(msg);
+            return true;
+        },
+        isAvailable() { return true; },
+        close() {
+            window.runtime.EventsOff('ai:state');
+            app.StopAI();
+        }
+    };
+}
+
+// preferences kept by the backend, so they survive a reinstall or follow
+// the player to another browser
+const settingKeys = [
+    'gameMode', 'sprintLines', 'ultraMinutes', 'marathonLines', 'digLines',
+    'ghostPieceEnabled', 'tetrixEnabled', 'das', 'arr', 'sdf',
+    'soundEnabled', 'volume', 'musicEnabled', 'musicVolume', 'aiOpponent', 'aiDemo'
+];
+
+// Copy the backend's preferences into localStorage once per app session,
+// then write them back whenever a page is left
+async function syncSettings() {
+    if (!sessionStorage.getItem('settingsLoaded')) {
+        try {
+            const stored = await getSettings();
+            for (const [k, v] of Object.entries(stored || {})) {
+                if (settingKeys.includes(k)) localStorage.setItem(k, v);
+            }
+            sessionStorage.setItem('settingsLoaded', '1');
+        } catch (e) {
+            console.warn('settings load failed', e);
+        }
+    }
+    window.addEventListener('pagehide', pushSettings);
+}
+
+// Write the preferences in localStorage to the backend now, for settings
+// the backend itself uses, such as the handling of held keys
+export function pushSettings() {
+    const settings = {};
+    for (const k of settingKeys) {
+        const v = localStorage.getItem(k);
+        if (v !== null) settings[k] = v;
+    }
+    return saveSettings(settings).catch(e => console.warn('settings save failed', e));
+}
+export const settingsReady = syncSettings();
@@ -0,0 +1,74 @@
+import { connectAI } from '../backend.js';
+import { createBoard } from '../game.js';
+
+// Runs a game the backend's AI plays on its own board: a versus opponent
+// beside the player's, or a demo on the start screen that starts over after
+// every game (loop).
+export class AIController {
+    constructor({ canvasId, previewId, scoreId, size = 18, loop = false }) {
+        this.canvasId = canvasId;
+        this.previewId = previewId;
+        this.scoreId = scoreId;
+        this.size = size;
+        this.loop = loop;
+        this.socket = null;
+        this.board = null;
+        this.paused = false;
+        this.ended = false;
+        this.restartTimer = null;
+    }
+
+    // Start the AI on a game: params are mode, lines, minutes and level
+    start(params) {
+        this.params = params;
+        this.board = createBoard(this.canvasId, this.previewId, this.size);
+        this.socket = connectAI(params, (state) => this.handleState(state));
+    }
+
+    handleState(state) {
+        // refused messages, such as inputs over the rate limit
+        if (state.type === 'error') {
+            console.warn('[AIController] Server refused a message:', state.code, state.error);
+            return;
+        }
+        this.board.draw(state);
+        this.paused = state.paused;
+        const ended = state.gameOver || state.completed;
+
+        const scoreEl = this.scoreId && document.getElementById(this.scoreId);
+        if (scoreEl) scoreEl.textContent = ended ? state.score + (state.completed ? ' – done' : ' – out') : state.score;
+
+        // a demo plays on after a short look at the end
+        if (ended && !this.ended && this.loop) {
+            this.restartTimer = setTimeout(() => this.restart(), 3000);
+        }
+        this.ended = ended;
+    }
+
+    // Pause or resume with the player's game; an ended game stays as it is
+    setPaused(paused) {
+        if (this.ended || paused === this.paused) return;
+        this.send({ type: 'pause/resume' });
+        this.paused = paused; // until its next state says otherwise
+    }
+
+    // Start the AI over, with the player's game
+    restart() {
+        clearTimeout(this.restartTimer);
+        this.paused = false;
+        const { mode, lines, minutes } = this.params;
+        this.send({ type: 'restart', mode, lines, minutes });
+    }
+
+    send(msg) {
+        if (this.socket && this.socket.isAvailable()) {
+            this.socket.send(msg);
+        }
+    }
+
+    stop() {
+        clearTimeout(this.restartTimer);
+        if (this.socket) this.socket.close();
+        this.socket = null;
+    }
+}
@@ -1,16 +1,22 @@
-import { createWS } from '../ws.js';
+import { desktop, backendURL, connectGame } from '../backend.js';
 import { initCanvas, drawState } from '../game.js';
 import { soundManager } from '../sounds.js';
-import { fetchHighscores, checkHighscore } from '../highscore.js';
+import { fetchHighscores, checkHighscore, formatTime } from '../highscore.js';
+import { AIController } from './aiController.js';
 
 export class GameController {
     constructor() {
         this.socket = null;
         this.lastScore = 0;
+        this.lastPhase = null;
         this.wasGameOver = false;
         this.lastPieceID = null;
         this.isPaused = false;
         this.mode = localStorage.getItem('gameMode') || 'beginner';
+        const defaultLines = { sprint: 40, marathon: 150, dig: 18 };
+        this.lines = parseInt(localStorage.getItem(this.mode + 'Lines') || defaultLines[this.mode] || 0, 10);
+        this.minutes = parseInt(localStorage.getItem('ultraMinutes') || '3', 10);
+        this.opponent = null; // the AI played against, when chosen in the settings
     }
 
     // Initialize the game controller
@@ -20,6 +26,7 @@ export class GameController {
         console.log('[GameController] Canvas initialized');
 
         this.setupWebSocket();
+        this.setupOpponent();
         this.fetchInitialState();
         console.log('[GameController] WebSocket setup and initial state fetch initiated');
         
@@ -29,11 +36,11 @@ export class GameController {
     
     // Setup WebSocket connection
     setupWebSocket() {
-        // In Wails, we need to connect to the backend on localhost:8081
-        const wsUrl = 'ws://localhost:8081/ws?mode=' + this.mode;
-        console.log('[GameController] Setting up WebSocket with URL:', wsUrl);
+        // Bindings in the desktop app, a websocket in a browser, see backend.js
+        const params = { mode: this.mode, lines: this.lines, minutes: this.minutes };
+        console.log('[GameController] Starting game with', params);
 
-        this.socket = createWS(wsUrl, (state) => {
+        this.socket = connectGame(params, (state) => {
             console.log('[GameController] Game state received');
             this.handleGameStateUpdate(state);
         }, () => {
@@ -43,7 +50,24 @@ export class GameController {
         });
     }
 
+    // Start the AI opponent at the level set in the settings, on the same mode
+    setupOpponent() {
+        const level = localStorage.getItem('aiOpponent');
+        const panel = document.getElementById('opponent');
+        if (!level || !panel) return;
+        panel.hidden = false;
+        const label = document.getElementById('aiLevel');
+        if (label) label.textContent = 'AI – ' + level;
+        this.opponent = new AIController({ canvasId: 'aiTetris', previewId: 'aiPreview', scoreId: 'aiScore' });
+        this.opponent.start({ mode: this.mode, lines: this.lines, minutes: this.minutes, level });
+    }
+
     handleGameStateUpdate(state) {
+        // refused messages, such as inputs over the rate limit
+        if (state.type === 'error') {
+            console.warn('[GameController] Server refused a message:', state.code, state.error);
+            return;
+        }
         drawState(state);
 
         // Show/hide next preview based on settings
@@ -58,32 +82,44 @@ export class GameController {
         }
         this.lastPieceID = state.pieceId;
 
-        // Detect line clear
-        if (state.score > this.lastScore) {
+        // Detect line clear: as the rows start clearing, or when the score
+        // rises in modes without a line clear delay
+        if (state.phase === 'clearing' && this.lastPhase !== 'clearing') {
+            soundManager.playLineClear();
+        } else if (state.score > this.lastScore && this.lastPhase !== 'clearing') {
             soundManager.playLineClear();
-            this.lastScore = state.score;
         }
+        this.lastScore = Math.max(this.lastScore, state.score);
+        this.lastPhase = state.phase;
+
+        // Detect game over transition, by topping out or completing the mode
+        const ended = state.gameOver || state.completed;
 
-        // Detect game over transition
-        if (state.gameOver && !this.wasGameOver) {
+        // the opponent waits while the player's game is paused or over
+        if (this.opponent) this.opponent.setPaused(state.paused || ended);
+        if (ended && !this.wasGameOver) {
             soundManager.playGameOver();
             soundManager.stopBackgroundMusic(); // Stop music when game is over
             this.wasGameOver = true;
 
             // Display final score for the submit modal
+            const result = this.resultFor(state);
             const finalScoreEl = document.getElementById("finalScore");
             if (finalScoreEl) {
                 finalScoreEl.textContent = "Score: " + state.score;
+                if (result.time) finalScoreEl.textContent = "Time: " + formatTime(result.time);
             }
+            this.lastResult = result;
+            this.showStats(state.stats);
 
             // Check if score is a new highscore (async now)
-            checkHighscore(state.score).then(isHighscore => {
+            checkHighscore(result).then(isHighscore => {
                 if (!isHighscore) {
                     const modal = document.getElementById('gameOverModal');
                     if (modal) modal.classList.add('show');
                 }
             });
-        } else if (!state.gameOver) {
+        } else if (!ended) {
             // Reset when game restarts
             this.wasGameOver = false;
         }
@@ -99,16 +135,46 @@ export class GameController {
         }
     }
 
+    // Fill the game over modal with the finished game's statistics
+    showStats(stats) {
+        const list = document.getElementById('gameStats');
+        if (!list) return;
+        list.replaceChildren();
+        if (!stats) return;
+        const pieces = Object.entries(stats.piecesByType || {})
+            .map(([name, n]) => name + ' ' + n).join(', ');
+        const faults = Object.entries(stats.finesseByType || {})
+            .map(([name, n]) => name + ' ' + n).join(', ');
+        const rows = [
+            ['Pieces', pieces || '-'],
+            ['Singles / Doubles / Triples', [stats.singles, stats.doubles, stats.triples].join(' / ')],
+            ['Tetrises', stats.tetrises],
+            ['T-spins', stats.tSpins],
+            ['Max combo', stats.maxCombo],
+            ['Holes', stats.holes],
+            ['Finesse faults', stats.finesseFaults + (faults ? ' (' + faults + ')' : '')],
+            ['Pieces per second', stats.pps.toFixed(2)],
+            ['Inputs per piece', stats.inputsPerPiece.toFixed(2)],
+        ];
+        for (const [label, value] of rows) {
+            const dt = document.createElement('dt');
+            dt.textContent = label;
+            const dd = document.createElement('dd');
+            dd.textContent = value;
+            list.append(dt, dd);
+        }
+    }
+
     //  Fetch initial game state if WebSocket is not yet available
     async fetchInitialState() {
         console.log('[GameController] Fetching initial state...');
-        if (this.socket && this.socket.isAvailable()) {
-            console.log('[GameController] WebSocket is available, skipping HTTP fetch');
+        if (desktop || (this.socket && this.socket.isAvailable())) {
+            console.log('[GameController] Game connected, skipping HTTP fetch');
             return;
         }
         try {
-            console.log('[GameController] Calling http://localhost:8081/board');
-            const res = await fetch('http://localhost:8081/board');
+            console.log('[GameController] Calling ' + backendURL + '/board');
+            const res = await fetch(backendURL + '/board');
             console.log('[GameController] Response status:', res.status);
             if (!res.ok) {
                 console.warn('[GameController] Board endpoint returned status:', res.status);
@@ -122,6 +188,32 @@ export class GameController {
         }
     }
 
+    // Leaderboard the selected mode is ranked on
+    board() {
+        switch (this.mode) {
+            case 'sprint':
+            case 'marathon':
+            case 'dig':
+                return { mode: this.mode, lines: this.lines };
+            case 'ultra':
+                return { mode: this.mode, minutes: this.minutes };
+            default:
+                return {};
+        }
+    }
+
+    // Build the leaderboard entry for a finished game
+    resultFor(state) {
+        // the server ranks the game its replay recorded, the rest is for show
+        const result = { score: state.score, replay: state.replay, ...this.board() };
+        if (state.mode.ranking === 'time') {
+            // only a completed run has a time to rank
+            result.time = state.completed ? state.elapsed : 0;
+            result.pieces = state.pieces;
+        }
+        return result;
+    }
+
     // Send control message to server
     sendControlMessage(message) {
         if (this.socket && this.socket.isAvailable()) {
@@ -136,7 +228,8 @@ export class GameController {
         soundManager.startBackgroundMusic();
         this.wasGameOver = false;
         this.lastScore = 0;
-        this.sendControlMessage({ type: 'restart', mode: this.mode });
+        this.sendControlMessage({ type: 'restart', mode: this.mode, lines: this.lines, minutes: this.minutes });
+        if (this.opponent) this.opponent.restart();
     }
 
     isGamePaused() {
@@ -1,8 +1,16 @@
+// Keys that repeat while held. The backend repeats them with the player's
+// DAS, ARR and soft drop settings, so only presses and releases are sent.
+const heldKeys = {
+    ArrowLeft: 'left', ArrowRight: 'right', ArrowDown: 'down',
+    a: 'left', A: 'left', d: 'right', D: 'right', s: 'down', S: 'down'
+};
+
 export class InputController {
     constructor() {
         this.gameController = null;
         this.modalController = null;
         this.controlsEnabled = true;
+        this.held = new Set(); // directions pressed and not yet released
     }
 
     // Initialize input listeners
@@ -10,6 +18,25 @@ export class InputController {
         document.addEventListener('keydown', (ev) => {
             this.handleKeyDown(ev);
         });
+        document.addEventListener('keyup', (ev) => {
+            this.handleKeyUp(ev);
+        });
+        // keys let go of while the window is in the background never send keyup
+        window.addEventListener('blur', () => this.releaseAll());
+    }
+
+    handleKeyUp(ev) {
+        const dir = heldKeys[ev.key];
+        if (!dir || !this.held.has(dir)) return;
+        this.held.delete(dir);
+        this.gameController.sendControlMessage({ type: 'release', dir });
+    }
+
+    releaseAll() {
+        for (const dir of this.held) {
+            this.gameController.sendControlMessage({ type: 'release', dir });
+        }
+        this.held.clear();
     }
 
     handleKeyDown(ev) {
@@ -41,25 +68,26 @@ export class InputController {
         if (!messageControl) return;
 
         ev.preventDefault(); // Only block the default handled keys
+        if (messageControl.type === 'press') {
+            // the OS key repeat is ignored; the backend repeats held keys
+            if (ev.repeat || this.held.has(messageControl.dir)) return;
+            this.held.add(messageControl.dir);
+        }
         this.gameController.sendControlMessage(messageControl);
     }
 
     getMovementControl(ev) {
-        // Arrow keys
-        if (ev.key === 'ArrowLeft') return { type: 'move', dir: 'left' };
-        if (ev.key === 'ArrowRight') return { type: 'move', dir: 'right' };
-        if (ev.key === 'ArrowDown') return { type: 'move', dir: 'down' };
+        // Arrow keys and WASD: held to move, up or W to rotate
+        if (heldKeys[ev.key]) return { type: 'press', dir: heldKeys[ev.key] };
         if (ev.key === 'ArrowUp') return { type: 'rotate' };
-
-        // WASD keys
-        if (ev.key === 'a' || ev.key === 'A') return { type: 'move', dir: 'left' };
-        if (ev.key === 'd' || ev.key === 'D') return { type: 'move', dir: 'right' };
-        if (ev.key === 's' || ev.key === 'S') return { type: 'move', dir: 'down' };
         if (ev.key === 'w' || ev.key === 'W') return { type: 'rotate' };
 
         // Space to drop
         if (ev.code === 'Space') return { type: 'drop' };
 
+        // Hold piece (modes with hold enabled)
+        if (ev.key === 'c' || ev.key === 'C' || ev.key === 'Shift') return { type: 'hold' };
+
         // Pausing game
         if (ev.key === 'p' || ev.key === 'P') return { type: 'pause/resume' };
 
@@ -30,11 +30,9 @@ export class ModalController {
     // Handle highscore submission
     async handleHighscoreSubmission(submitBtn, nameInput) {
         const name = nameInput.value.trim() || 'Anonymous';
-        const scoreText = document.getElementById('finalScore').textContent || '';
-        const m = scoreText.match(/(\d+)/);
-        const score = m ? parseInt(m[1], 10) : 0;
+        const result = this.gameController.lastResult || { score: 0 };
 
-        const ok = await submitHighscore(name, score);
+        const ok = await submitHighscore(name, result);
         submitBtn.disabled = true;
 
         // Handle submission result
@@ -23,6 +23,18 @@ export function drawState(state) {
     renderer.drawState(state);
 }
 
+// Sets up another board, such as the AI's, on its own canvases. It only
+// draws them: score and modals stay with the player's board.
+export function createBoard(mainId, previewId, size) {
+    const canvases = new CanvasManager();
+    canvases.initCanvas(mainId, previewId, size);
+    const ghosts = new GhostPieceRenderer(canvases, new CollisionDetector(canvases));
+    const board = new Renderer(canvases, ghosts, new PreviewRenderer(canvases), null);
+    return {
+        draw(state) { board.drawState(state); }
+    };
+}
+
 export function clear() {
     canvasManager.clear();
 }
@@ -1,9 +1,20 @@
+import { getHighscores, postHighscore } from './backend.js';
+
+// leaderboard shown on the page, set by the last fetch
+let currentBoard = {};
+
+// format milliseconds as m:ss.mmm
+export function formatTime(ms) {
+    const m = Math.floor(ms / 60000);
+    const s = Math.floor((ms % 60000) / 1000);
+    return m + ':' + String(s).padStart(2, '0') + '.' + String(ms % 1000).padStart(3, '0');
+}
+
 // fetch highscores from server 
-export async function fetchHighscores() {
+export async function fetchHighscores(board = currentBoard) {
+    currentBoard = board;
     try {
-        const res = await fetch('http://localhost:8081/highscores');
-        if (!res.ok) return [];
-        const hs = await res.json();
+        const hs = await getHighscores(board);
         renderHighscores(hs);
         return hs;
     } catch (e) {
@@ -54,7 +65,7 @@ export function renderHighscores(list) {
         
         const scoreSpan = document.createElement('span');
         scoreSpan.className = 'entry-score';
-        scoreSpan.textContent = entry.score.toLocaleString();
+        scoreSpan.textContent = entry.time ? formatTime(entry.time) : entry.score.toLocaleString();
         
         const dateSpan = document.createElement('span');
         dateSpan.className = 'entry-date';
@@ -76,14 +87,9 @@ export function renderHighscores(list) {
 }
 
 // send highscore
-export async function submitHighscore(name, score) {
+export async function submitHighscore(name, result) {
     try {
-        const res = await fetch('http://localhost:8081/highscores', {
-            method: 'POST',
-            headers: {'Content-Type': 'application/json'},
-            body: JSON.stringify({ name, score })
-        });
-        if (!res.ok) throw new Error('failed');
+        await postHighscore({ name, ...result });
         await fetchHighscores(); // update list
         return true;
     } catch (e) {
@@ -93,15 +99,15 @@ export async function submitHighscore(name, score) {
 }
 
 // check if score qualifies as highscore
-export async function checkHighscore(score) {
+export async function checkHighscore(result) {
     try {
-        const res = await fetch('http://localhost:8081/highscores');
-        if (!res.ok) return false;
-        const highscores = await res.json();
+        const highscores = await getHighscores(result);
 
-        const qualifies =
-            highscores.length < 10 ||
-            score > highscores[highscores.length - 1].score;
+        // timed boards only accept completed runs, fastest first
+        const last = highscores[highscores.length - 1];
+        const qualifies = result.time !== undefined
+            ? result.time > 0 && (highscores.length < 10 || result.time < last.time)
+            : highscores.length < 10 || result.score > last.score;
         if (qualifies) {
             document.getElementById("highscoreModal").classList.add("show");
             return true;
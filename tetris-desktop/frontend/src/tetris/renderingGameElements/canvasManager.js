@@ -25,6 +25,18 @@ export class CanvasManager {
         if (this.previewCanvas) this.previewCtx = this.previewCanvas.getContext('2d');
     }
 
+    // Resizes the main canvas to the board's visible size
+    resize(cols, rows) {
+        if (!this.canvas || !cols || !rows) return;
+        if (cols === this.COLS && rows === this.ROWS) return;
+        this.COLS = cols;
+        this.ROWS = rows;
+        this.canvas.width = this.COLS * this.cellSize;
+        this.canvas.height = this.ROWS * this.cellSize;
+        this.canvas.style.width = this.canvas.width + 'px';
+        this.canvas.style.height = this.canvas.height + 'px';
+    }
+
     // Clears the main canvas by filling it with black
     clear() {
         if (!this.ctx) return;
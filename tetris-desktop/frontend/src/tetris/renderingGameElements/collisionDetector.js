@@ -1,3 +1,8 @@
+// Returns the side of a flattened square piece
+export function pieceSize(piece) {
+    return Math.round(Math.sqrt(piece.length));
+}
+
 // Detects collisions between Tetris pieces and the game board
 export class CollisionDetector {
     // Initializes the CollisionDetector with a reference to the CanvasManager
@@ -7,13 +12,14 @@ export class CollisionDetector {
 
     // Checks if a piece collides with the board or existing pieces
     collides(board, piece, px, py) {
-        const COLS = 10; // Board width
-        const ROWS = 20; // Board height
+        const ROWS = board.length; // Board height, hidden rows included
+        const COLS = ROWS ? board[0].length : 0; // Board width
 
-        // Check each cell of the 4x4 piece matrix
-        for (let y = 0; y < 4; y++) {
-            for (let x = 0; x < 4; x++) {
-                const val = piece[y * 4 + x];
+        // Check each cell of the square piece matrix
+        const n = pieceSize(piece);
+        for (let y = 0; y < n; y++) {
+            for (let x = 0; x < n; x++) {
+                const val = piece[y * n + x];
                 if (val === 0) continue; // Empty cell in piece
 
                 const bx = px + x; // Board X coordinate
@@ -15,6 +15,7 @@ export class ColorManager {
             case 10: return '#f0c000'; // Additional yellow variant
             case 11: return '#3050f0'; // Additional blue variant
             case 12: return '#f03030'; // Additional red variant
+            case 13: return '#808080'; // Garbage (gray)
             default: return '#666'; // Default gray for unknown pieces
         }
     }
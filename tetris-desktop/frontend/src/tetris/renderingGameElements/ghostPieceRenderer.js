@@ -1,4 +1,5 @@
 import { ColorManager } from './colorManager.js';
+import { pieceSize } from './collisionDetector.js';
 
 // Renders the ghost piece on the Tetris board
 export class GhostPieceRenderer {
@@ -24,12 +25,14 @@ export class GhostPieceRenderer {
             gy++; // Drop the piece down until it collides
         }
 
-        // Render each cell of the ghost piece
-        for (let y = 0; y < 4; y++) {
-            for (let x = 0; x < 4; x++) {
-                const v = state.piece[y * 4 + x];
-                if (v) {
-                    this.drawGhostCell(state.x + x, gy + y, v);
+        // Render each cell of the ghost piece, skipping the hidden rows
+        const hidden = state.hidden || 0;
+        const n = pieceSize(state.piece);
+        for (let y = 0; y < n; y++) {
+            for (let x = 0; x < n; x++) {
+                const v = state.piece[y * n + x];
+                if (v && gy + y >= hidden) {
+                    this.drawGhostCell(state.x + x, gy + y - hidden, v);
                 }
             }
         }
@@ -1,4 +1,5 @@
 import { ColorManager } from './colorManager.js';
+import { pieceSize } from './collisionDetector.js';
 
 // Renders the next piece in the preview canvas
 export class PreviewRenderer {
@@ -18,17 +19,19 @@ export class PreviewRenderer {
         previewCtx.fillStyle = '#000';
         previewCtx.fillRect(0, 0, previewCanvas.width, previewCanvas.height);
 
-        // Calculate cell size for the preview (smaller than main board)
-        const cell = Math.floor(previewCanvas.width / 4);
+        // Calculate cell size for the preview (smaller than main board),
+        // never larger than a 4-cell box so small pieces keep their scale
+        const n = pieceSize(flatPiece);
+        const cell = Math.floor(previewCanvas.width / Math.max(n, 4));
 
         // Center the piece in the preview canvas
-        const startX = Math.floor((previewCanvas.width - (cell * 4)) / 2);
-        const startY = Math.floor((previewCanvas.height - (cell * 4)) / 2);
+        const startX = Math.floor((previewCanvas.width - (cell * n)) / 2);
+        const startY = Math.floor((previewCanvas.height - (cell * n)) / 2);
 
         // Render each cell of the piece
-        for (let y = 0; y < 4; y++) {
-            for (let x = 0; x < 4; x++) {
-                const v = flatPiece[y * 4 + x];
+        for (let y = 0; y < n; y++) {
+            for (let x = 0; x < n; x++) {
+                const v = flatPiece[y * n + x];
                 if (v) {
                     this.drawPreviewCell(previewCtx, startX + x * cell, startY + y * cell, cell, ColorManager.colorFor(v));
                 }
@@ -1,8 +1,10 @@
 import { ColorManager } from './colorManager.js';
+import { pieceSize } from './collisionDetector.js';
 
 // Renders the main game elements on the Tetris board
 export class Renderer {
-    // Initializes the Renderer with references to other rendering components
+    // Initializes the Renderer with references to other rendering components;
+    // without a uiManager it only draws the canvases
     constructor(canvasManager, ghostPieceRenderer, previewRenderer, uiManager) {
         this.canvasManager = canvasManager;
         this.ghostPieceRenderer = ghostPieceRenderer;
@@ -15,32 +17,41 @@ export class Renderer {
         if (!state) return;
         if (!this.canvasManager.getContext()) return;
 
-        // Clear the canvas for fresh rendering
+        // Match the canvas to the visible field and clear it
+        this.canvasManager.resize(state.width, state.height);
         this.canvasManager.clear();
+        const hidden = state.hidden || 0;
 
         // Render ghost piece if enabled in game mode
         if (state.mode.ghostPiece) {
             this.ghostPieceRenderer.drawGhostPiece(state);
         }
 
-        // Render the game board (placed pieces)
+        // Render the game board (placed pieces), skipping the hidden rows
         if (Array.isArray(state.board)) {
-            for (let y = 0; y < state.board.length; y++) {
+            for (let y = hidden; y < state.board.length; y++) {
                 for (let x = 0; x < state.board[y].length; x++) {
-                    const v = state.board[y][x];
-                    if (v) this.drawCell(x, y, ColorManager.colorFor(v));
+                    // cells are packed; the colour is the low byte
+                    const v = state.board[y][x] & 0xff;
+                    if (v) this.drawCell(x, y - hidden, ColorManager.colorFor(v));
                 }
             }
         }
 
+        // Flash the rows being cleared, fading as the line clear delay runs out
+        if (Array.isArray(state.clearing) && state.mode.lineClearDelay) {
+            this.drawClearing(state.clearing, hidden, state.phaseLeft / state.mode.lineClearDelay);
+        }
+
         // Render the currently falling piece
         const piece = state.piece || [];
         const px = Number.isFinite(state.x) ? state.x : 0;
         const py = Number.isFinite(state.y) ? state.y : 0;
-        for (let y = 0; y < 4; y++) {
-            for (let x = 0; x < 4; x++) {
-                const v = piece[y * 4 + x];
-                if (v) this.drawCell(px + x, py + y, ColorManager.colorFor(v));
+        const n = pieceSize(piece);
+        for (let y = 0; y < n; y++) {
+            for (let x = 0; x < n; x++) {
+                const v = piece[y * n + x];
+                if (v && py + y >= hidden) this.drawCell(px + x, py + y - hidden, ColorManager.colorFor(v));
             }
         }
 
@@ -49,11 +60,31 @@ export class Renderer {
             this.previewRenderer.drawPreview(state.next[0]);
         }
 
-        // Update UI elements
+        // Update UI elements, on the player's board only
+        if (!this.uiManager) return;
         this.uiManager.updateScore(state.score);
+        if (state.mode.goal.garbage) {
+            this.uiManager.updateTime(state.elapsed, state.garbageLeft + ' left');
+        } else if (state.mode.ranking === 'time') {
+            this.uiManager.updateTime(state.elapsed, state.lines + '/' + state.mode.goal.lines);
+        } else if (state.mode.goal.timeLimit) {
+            this.uiManager.updateCountdown(state.mode.goal.timeLimit - state.elapsed, state.score);
+        }
+        this.uiManager.updateFinesse(state.finesse);
         this.uiManager.handlePauseModal(state.paused);
     }
 
+    // Draws a white band over each clearing row, at opacity left (0..1)
+    drawClearing(rows, hidden, left) {
+        const ctx = this.canvasManager.getContext();
+        const cellSize = this.canvasManager.getCellSize();
+        if (!ctx) return;
+        ctx.fillStyle = 'rgba(255, 255, 255, ' + Math.max(0, Math.min(1, left)) * 0.8 + ')';
+        for (const y of rows) {
+            if (y >= hidden) ctx.fillRect(0, (y - hidden) * cellSize, ctx.canvas.width, cellSize);
+        }
+    }
+
     // Draws a single cell at the specified board coordinates with the given color
     drawCell(x, y, color) {
         const ctx = this.canvasManager.getContext();
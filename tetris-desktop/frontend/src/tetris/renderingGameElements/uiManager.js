@@ -1,3 +1,5 @@
+import { formatTime } from '../highscore.js';
+
 // Manages UI elements like score display and pause modal
 export class UIManager {
     // Updates the score display element
@@ -6,6 +8,36 @@ export class UIManager {
         if (scoreEl) scoreEl.textContent = score || 0;
     }
 
+    // Shows timer and goal progress for timed modes in the score display
+    updateTime(elapsed, progress) {
+        const scoreEl = document.getElementById('score');
+        if (scoreEl) scoreEl.textContent = formatTime(elapsed || 0) + ' (' + progress + ')';
+    }
+
+    // Shows time left and score for time-limited modes
+    updateCountdown(remaining, score) {
+        const scoreEl = document.getElementById('score');
+        if (scoreEl) scoreEl.textContent = (score || 0) + ' – ' + formatTime(Math.max(0, remaining));
+    }
+
+    // Shows how the last piece was placed against the fewest key presses,
+    // flashing the panel when a new fault comes in
+    updateFinesse(check) {
+        const panel = document.getElementById('finesse-panel');
+        const el = document.getElementById('finesse');
+        if (!panel || !el) return;
+        panel.hidden = !check;
+        if (!check || check.number === this.lastFinesse) return;
+        this.lastFinesse = check.number;
+        if (check.fault) {
+            el.textContent = check.piece + ': ' + check.inputs + ' presses, ' + check.least +
+                ' needed (' + check.path.join(' ') + ')' + (check.retry ? ' – try again' : '');
+        } else {
+            el.textContent = check.piece + ': ' + check.inputs + ' presses';
+        }
+        el.classList.toggle('fault', check.fault);
+    }
+
     // Handles showing or hiding the pause modal based on game state
     handlePauseModal(paused) {
         const pauseModal = document.getElementById('pauseModal');
@@ -21,5 +21,5 @@ export default function initUI() {
     inputController.init();
 
     // Load initial highscores
-    fetchHighscores();
+    fetchHighscores(gameController.board());
 }
\ No newline at end of file
@@ -1,4 +1,42 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
+import {server} from '../models';
+import {main} from '../models';
 
-export function Greet(arg1:string):Promise<string>;
+export function AIInput(arg1:Record<string, any>):Promise<void>;
+
+export function BackendURL():Promise<string>;
+
+export function BackendToken():Promise<string>;
+
+export function DeleteReplay(arg1:string):Promise<void>;
+
+export function DeleteSave(arg1:string):Promise<void>;
+
+export function GameInput(arg1:Record<string, any>):Promise<void>;
+
+export function Highscores(arg1:string,arg2:number,arg3:number):Promise<Array<server.Highscore>>;
+
+export function Modes():Promise<server.ModeSet>;
+
+export function Replay(arg1:string):Promise<server.Replay>;
+
+export function Replays():Promise<Array<server.Replay>>;
+
+export function SaveSettings(arg1:Record<string, string>):Promise<void>;
+
+export function Saves():Promise<Array<server.Replay>>;
+
+export function Settings():Promise<Record<string, string>>;
+
+export function StartAI(arg1:main.AIOptions):Promise<void>;
+
+export function StartGame(arg1:main.GameOptions):Promise<void>;
+
+export function Stats():Promise<server.LifetimeStats>;
+
+export function StopAI():Promise<void>;
+
+export function StopGame():Promise<void>;
+
+export function SubmitHighscore(arg1:server.ScoreSubmission):Promise<void>;
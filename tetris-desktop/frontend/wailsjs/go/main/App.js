@@ -2,6 +2,78 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
-export function Greet(arg1) {
-  return window['go']['main']['App']['Greet'](arg1);
+export function AIInput(arg1) {
+  return window['go']['main']['App']['AIInput'](arg1);
+}
+
+export function BackendURL() {
+  return window['go']['main']['App']['BackendURL']();
+}
+
+export function BackendToken() {
+  return window['go']['main']['App']['BackendToken']();
+}
+
+export function DeleteReplay(arg1) {
+  return window['go']['main']['App']['DeleteReplay'](arg1);
+}
+
+export function DeleteSave(arg1) {
+  return window['go']['main']['App']['DeleteSave'](arg1);
+}
+
+export function GameInput(arg1) {
+  return window['go']['main']['App']['GameInput'](arg1);
+}
+
+export function Highscores(arg1, arg2, arg3) {
+  return window['go']['main']['App']['Highscores'](arg1, arg2, arg3);
+}
+
+export function Modes() {
+  return window['go']['main']['App']['Modes']();
+}
+
+export function Replay(arg1) {
+  return window['go']['main']['App']['Replay'](arg1);
+}
+
+export function Replays() {
+  return window['go']['main']['App']['Replays']();
+}
+
+export function SaveSettings(arg1) {
+  return window['go']['main']['App']['SaveSettings'](arg1);
+}
+
+export function Saves() {
+  return window['go']['main']['App']['Saves']();
+}
+
+export function Settings() {
+  return window['go']['main']['App']['Settings']();
+}
+
+export function StartAI(arg1) {
+  return window['go']['main']['App']['StartAI'](arg1);
+}
+
+export function StartGame(arg1) {
+  return window['go']['main']['App']['StartGame'](arg1);
+}
+
+export function Stats() {
+  return window['go']['main']['App']['Stats']();
+}
+
+export function StopAI() {
+  return window['go']['main']['App']['StopAI']();
+}
+
+export function StopGame() {
+  return window['go']['main']['App']['StopGame']();
+}
+
+export function SubmitHighscore(arg1) {
+  return window['go']['main']['App']['SubmitHighscore'](arg1);
 }
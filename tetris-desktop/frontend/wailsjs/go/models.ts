@@ -0,0 +1,208 @@
+export namespace main {
+	
+	export class AIOptions {
+	    mode: string;
+	    lines: number;
+	    minutes: number;
+	    level: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new AIOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.mode = source["mode"];
+	        this.lines = source["lines"];
+	        this.minutes = source["minutes"];
+	        this.level = source["level"];
+	    }
+	}
+	export class GameOptions {
+	    mode: string;
+	    lines: number;
+	    minutes: number;
+	    save: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new GameOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.mode = source["mode"];
+	        this.lines = source["lines"];
+	        this.minutes = source["minutes"];
+	        this.save = source["save"];
+	    }
+	}
+
+}
+
+export namespace server {
+	
+	export class Highscore {
+	    name: string;
+	    score: number;
+	    mode?: string;
+	    lines?: number;
+	    minutes?: number;
+	    time?: number;
+	    pieces?: number;
+	    when: any;
+	    replay?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Highscore(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.score = source["score"];
+	        this.mode = source["mode"];
+	        this.lines = source["lines"];
+	        this.minutes = source["minutes"];
+	        this.time = source["time"];
+	        this.pieces = source["pieces"];
+	        this.when = source["when"];
+	        this.replay = source["replay"];
+	    }
+	}
+	export class LifetimeStats {
+	    games: number;
+	    playTime: number;
+	    pieces: number;
+	    lines: number;
+	    score: number;
+	    piecesByType: Record<string, number>;
+	    singles: number;
+	    doubles: number;
+	    triples: number;
+	    tetrises: number;
+	    tSpins: number;
+	    maxCombo: number;
+	    holes: number;
+	    finesseFaults: number;
+	    finesseByType: Record<string, number>;
+	    inputs: number;
+	    pps: number;
+	    inputsPerPiece: number;
+	    modes: Record<string, any>;
+	
+	    static createFrom(source: any = {}) {
+	        return new LifetimeStats(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.games = source["games"];
+	        this.playTime = source["playTime"];
+	        this.pieces = source["pieces"];
+	        this.lines = source["lines"];
+	        this.score = source["score"];
+	        this.piecesByType = source["piecesByType"];
+	        this.singles = source["singles"];
+	        this.doubles = source["doubles"];
+	        this.triples = source["triples"];
+	        this.tetrises = source["tetrises"];
+	        this.tSpins = source["tSpins"];
+	        this.maxCombo = source["maxCombo"];
+	        this.holes = source["holes"];
+	        this.finesseFaults = source["finesseFaults"];
+	        this.finesseByType = source["finesseByType"];
+	        this.inputs = source["inputs"];
+	        this.pps = source["pps"];
+	        this.inputsPerPiece = source["inputsPerPiece"];
+	        this.modes = source["modes"];
+	    }
+	}
+	export class ModeSet {
+	    default: string;
+	    modes: any[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ModeSet(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.default = source["default"];
+	        this.modes = source["modes"];
+	    }
+	}
+	export class ReplayEvent {
+	    t: number;
+	    type: string;
+	    dir?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ReplayEvent(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.t = source["t"];
+	        this.type = source["type"];
+	        this.dir = source["dir"];
+	    }
+	}
+	export class Replay {
+	    id: string;
+	    name?: string;
+	    when: any;
+	    mode: any;
+	    seed: number;
+	    score: number;
+	    lines: number;
+	    pieces: number;
+	    elapsed: number;
+	    ended: boolean;
+	    handling?: any;
+	    stats?: any;
+	    events?: ReplayEvent[];
+	
+	    static createFrom(source: any = {}) {
+	        return new Replay(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.when = source["when"];
+	        this.mode = source["mode"];
+	        this.seed = source["seed"];
+	        this.score = source["score"];
+	        this.lines = source["lines"];
+	        this.pieces = source["pieces"];
+	        this.elapsed = source["elapsed"];
+	        this.ended = source["ended"];
+	        this.handling = source["handling"];
+	        this.stats = source["stats"];
+	        this.events = source["events"];
+	    }
+	}
+	export class ScoreSubmission {
+	    name: string;
+	    mode: string;
+	    lines: number;
+	    minutes: number;
+	    replay: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ScoreSubmission(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.mode = source["mode"];
+	        this.lines = source["lines"];
+	        this.minutes = source["minutes"];
+	        this.replay = source["replay"];
+	    }
+	}
+
+}
+
@@ -1,18 +1,18 @@
 package main
 
 import (
+	"context"
 	"embed"
-	"encoding/json"
-	"io"
+	"flag"
+	"fmt"
 	"io/fs"
-	"log"
-	"net/http"
+	"log/slog"
 	"os"
-	"sort"
+	"os/signal"
 	"strings"
-	"sync"
-	"time"
+	"syscall"
 
+	"tetris-desktop/backend/logging"
 	"tetris-desktop/backend/server"
 
 	"github.com/wailsapp/wails/v2"
@@ -23,213 +23,85 @@ import (
 //go:embed all:frontend/dist
 var embeddedAssets embed.FS
 
-// Highscore entry
-type Highscore struct {
-	Name  string    `json:"name"`
-	Score int       `json:"score"`
-	When  time.Time `json:"when"`
-}
-
 var (
-	hsFile     string
-	hsMu       sync.Mutex
-	highscores []Highscore
-	maxHS      = 10
+	headless = flag.Bool("headless", false, "serve the game to browsers over HTTP instead of opening a window")
+	addr     = flag.String("addr", server.DefaultAddr, "address the backend listens on, host:port")
+	dataDir  = flag.String("data", server.DataDir(), "directory holding highscores, modes.json and pieces.json")
+	api      = flag.Bool("api", false, "also serve the HTTP API next to the window, for bots and other clients")
+	ports    = flag.String("ports", "", "ports to try when the --addr port is taken, first-last; any free port if empty")
+	noToken  = flag.Bool("no-token", false, "let API clients in without the per-launch access token")
+	origins  = flag.String("origins", "", "comma-separated browser origins allowed to use the API besides the backend's own")
+	sendRate = flag.Int("send-rate", 60, "most game states sent to a client per second")
+	logFlags = logging.Flags(func() string { return *dataDir })
 )
 
-func init() {
-	// Use executable directory for highscores file
-	exePath, err := os.Executable()
+func main() {
+	flag.Parse()
+	closeLog, err := logging.Setup(logFlags())
 	if err != nil {
-		log.Println("Failed to get executable path:", err)
-		hsFile = "highscores.json"
-	} else {
-		exeDir := strings.TrimSuffix(exePath, "\\"+os.Args[0])
-		if idx := strings.LastIndex(exePath, "\\"); idx >= 0 {
-			exeDir = exePath[:idx]
-		}
-		hsFile = exeDir + "\\highscores.json"
+		logging.Fatal("Cannot open the log file", "err", err)
 	}
-	log.Println("Highscores file:", hsFile)
-}
+	defer closeLog()
 
-// load highscores from file (call once at startup)
-func loadHighscores() {
-	hsMu.Lock()
-	defer hsMu.Unlock()
-
-	f, err := os.Open(hsFile)
+	// Strip the frontend/dist prefix from embedded files
+	assets, err := fs.Sub(embeddedAssets, "frontend/dist")
 	if err != nil {
-		if os.IsNotExist(err) {
-			highscores = []Highscore{}
-			return
-		}
-		log.Println("loadHighscores open:", err)
-		highscores = []Highscore{}
-		return
-	}
-	defer f.Close()
-	dec := json.NewDecoder(f)
-	var hs []Highscore
-	if err := dec.Decode(&hs); err != nil && err != io.EOF {
-		log.Println("loadHighscores decode:", err)
-		highscores = []Highscore{}
-		return
+		logging.Fatal("Frontend assets missing", "err", err)
 	}
-	highscores = hs
-}
 
-// save highscores to file
-func saveHighscores() {
-	hsMu.Lock()
-	defer hsMu.Unlock()
-	tmp := hsFile + ".tmp"
-	f, err := os.Create(tmp)
-	if err != nil {
-		log.Println("saveHighscores create tmp:", err)
-		return
+	srv := server.New()
+	if *sendRate <= 0 {
+		logging.Fatal("Invalid --send-rate", "rate", *sendRate)
 	}
-	enc := json.NewEncoder(f)
-	enc.SetIndent("", "  ")
-	if err := enc.Encode(highscores); err != nil {
-		log.Println("saveHighscores encode:", err)
-		f.Close()
-		return
+	srv.SendRate = *sendRate
+	cfg := server.Config{Addr: *addr, DataDir: *dataDir, Origins: splitList(*origins)}
+	if !*noToken {
+		cfg.Token = server.NewToken()
 	}
-	f.Close()
-	if err := os.Rename(tmp, hsFile); err != nil {
-		log.Println("saveHighscores rename:", err)
+	if *ports != "" {
+		r, err := server.ParsePortRange(*ports)
+		if err != nil {
+			logging.Fatal("Invalid --ports", "err", err)
+		}
+		cfg.Ports = &r
 	}
-}
-
-// GET /highscores and POST /highscores handler
-func highscoresHandler(w http.ResponseWriter, r *http.Request) {
-	// Add CORS headers
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-
-	if r.Method == http.MethodOptions {
-		w.WriteHeader(http.StatusOK)
-		return
+	if *headless {
+		// browsers load the frontend from the backend itself
+		cfg.Assets = assets
 	}
-
-	switch r.Method {
-	case http.MethodGet:
-		log.Println("GET /highscores - returning", len(highscores), "entries")
-		hsMu.Lock()
-		out := make([]Highscore, len(highscores))
-		copy(out, highscores)
-		hsMu.Unlock()
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(out)
-		return
-
-	case http.MethodPost:
-		log.Println("POST /highscores - receiving new score")
-		var req struct {
-			Name  string `json:"name"`
-			Score int    `json:"score"`
-		}
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			log.Println("POST /highscores - decode error:", err)
-			http.Error(w, "invalid body", http.StatusBadRequest)
-			return
-		}
-		log.Println("POST /highscores - name:", req.Name, "score:", req.Score)
-		name := strings.TrimSpace(req.Name)
-		if name == "" {
-			name = "Anonymous"
-		}
-		if len(name) > 20 {
-			name = name[:20]
-		}
-		entry := Highscore{
-			Name:  name,
-			Score: req.Score,
-			When:  time.Now().UTC(),
+	if err := srv.Setup(cfg); err != nil {
+		logging.Fatal("Invalid data file", "err", err)
+	}
+	if *headless {
+		if err := srv.Listen(); err != nil {
+			logging.Fatal("Backend server not started", "err", err)
 		}
-
-		hsMu.Lock()
-		// insert and keep sorted desc
-		highscores = append(highscores, entry)
-		sort.SliceStable(highscores, func(i, j int) bool {
-			return highscores[i].Score > highscores[j].Score
-		})
-		if len(highscores) > maxHS {
-			highscores = highscores[:maxHS]
+		// printed rather than logged, so the token stays out of the log file
+		fmt.Fprintln(os.Stderr, "Open the game in a browser to play:", srv.PageURL())
+		go shutdownOnSignal(srv)
+		if err := srv.ListenAndServe(); err != nil {
+			logging.Fatal("Backend server stopped", "err", err)
 		}
-		hsMu.Unlock()
-
-		// persist async-safe (but quick)
-		saveHighscores()
-
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]any{"ok": true})
-		return
-	default:
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
-}
-
-func startBackendServer() {
-	// Load highscores at startup
-	loadHighscores()
-
-	// API endpoints
-	http.HandleFunc("/start", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodPost {
-			w.WriteHeader(http.StatusMethodNotAllowed)
-			return
-		}
-		log.Println("Game started")
-		w.WriteHeader(http.StatusOK)
-	})
-
-	http.HandleFunc("/highscores", highscoresHandler)
-
-	http.HandleFunc("/restart", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodPost {
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte(`{"status":"ok"}`))
-		}
-	})
-
-	http.HandleFunc("/quit", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodPost {
-			w.WriteHeader(http.StatusMethodNotAllowed)
-			return
-		}
-		log.Println("Game quit")
-		w.WriteHeader(http.StatusOK)
-		os.Exit(0)
-	})
-
-	// instantiate server and register its handlers
-	srv := server.New()
-	srv.RegisterHandlers()
-
-	go func() {
-		log.Println("Backend server listening on :8081")
-		log.Fatal(http.ListenAndServe(":8081", nil))
-	}()
-
-	// Wait for server to start
-	time.Sleep(100 * time.Millisecond)
-}
-
-func main() {
-	// Start the backend server
-	startBackendServer()
 
 	// Create an instance of the app structure
-	app := NewApp()
-
-	// Strip the frontend/dist prefix from embedded files
-	assets, err := fs.Sub(embeddedAssets, "frontend/dist")
-	if err != nil {
-		log.Fatal(err)
+	app := NewApp(srv)
+
+	// The window talks to the app through its bindings; the HTTP API is
+	// only opened when asked for. If it cannot start the game still runs,
+	// and the player is told why once the window is up.
+	if *api {
+		if err := srv.Listen(); err != nil {
+			app.apiErr = err
+		} else {
+			srv.OnQuit = app.quit
+			go func() {
+				if err := srv.ListenAndServe(); err != nil {
+					slog.Error("Backend server stopped", "err", err)
+				}
+			}()
+		}
 	}
 
 	// Create application with options
@@ -242,6 +114,8 @@ func main() {
 		},
 		BackgroundColour: &options.RGBA{R: 27, G: 38, B: 54, A: 1},
 		OnStartup:        app.startup,
+		OnDomReady:       app.domReady,
+		OnShutdown:       app.shutdown,
 		Bind: []interface{}{
 			app,
 		},
@@ -251,3 +125,26 @@ func main() {
 		println("Error:", err.Error())
 	}
 }
+
+// splitList splits a comma-separated flag value, dropping empty items
+func splitList(s string) []string {
+	var out []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// shutdownOnSignal shuts the server down cleanly on Ctrl-C or SIGTERM
+func shutdownOnSignal(srv *server.Server) {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	<-sig
+	ctx, cancel := context.WithTimeout(context.Background(), server.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		slog.Error("Shutdown incomplete", "err", err)
+	}
+}